@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// activeMission is a mission that has been launched but not yet collected.
+type activeMission struct {
+	Id               string
+	Ship             ei.MissionInfo_Spaceship
+	ShipName         string
+	DurationType     ei.MissionInfo_DurationType
+	DurationTypeName string
+	Level            uint32
+	Status           ei.MissionInfo_Status
+	StatusName       string
+	LaunchedAt       time.Time
+	LaunchedAtStr    string
+	ReturnsAt        time.Time
+	ReturnsAtStr     string
+	SecondsRemaining float64
+	Capacity         uint32
+	Fuels            []*ei.MissionInfo_Fuel
+	FuelStr          string
+}
+
+// newActiveMission creates an activeMission from a mission in a backup.
+// seconds_remaining in the backup is relative to the backup time, so it is
+// recomputed relative to now.
+func newActiveMission(info *ei.MissionInfo, backupTime time.Time, now time.Time) *activeMission {
+	ship := info.GetShip()
+	durationType := info.GetDurationType()
+	status := info.GetStatus()
+	duration := time.Duration(info.GetDurationSeconds()) * time.Second
+	var launchedAt, returnsAt time.Time
+	if info.GetStartTimeDerived() > 0 {
+		launchedAt = unixToTime(info.GetStartTimeDerived()).Truncate(time.Second)
+		returnsAt = launchedAt.Add(duration)
+	} else {
+		returnsAt = backupTime.Add(time.Duration(info.GetSecondsRemaining() * float64(time.Second))).Truncate(time.Second)
+		launchedAt = returnsAt.Add(-duration)
+	}
+	secondsRemaining := returnsAt.Sub(now).Seconds()
+	if secondsRemaining < 0 || status == ei.MissionInfo_RETURNED {
+		secondsRemaining = 0
+	}
+	var fuels []string
+	for _, f := range info.GetFuel() {
+		fuels = append(fuels, fmt.Sprintf("%s: %s", f.GetEgg().Display(), formatEggAmount(f.GetAmount())))
+	}
+	return &activeMission{
+		Id:               info.GetIdentifier(),
+		Ship:             ship,
+		ShipName:         ship.Name(),
+		DurationType:     durationType,
+		DurationTypeName: durationType.Display(),
+		Level:            info.GetLevel(),
+		Status:           status,
+		StatusName:       status.Display(),
+		LaunchedAt:       launchedAt,
+		LaunchedAtStr:    launchedAt.Format(time.RFC3339),
+		ReturnsAt:        returnsAt,
+		ReturnsAtStr:     returnsAt.Format(time.RFC3339),
+		SecondsRemaining: secondsRemaining,
+		Capacity:         info.GetCapacity(),
+		Fuels:            info.GetFuel(),
+		FuelStr:          strings.Join(fuels, "; "),
+	}
+}
+
+// Summary is a one-line human readable description of the mission's return
+// schedule.
+func (m *activeMission) Summary() string {
+	s := fmt.Sprintf("%s (%s)", m.ShipName, m.DurationTypeName)
+	if m.SecondsRemaining > 0 {
+		s += fmt.Sprintf(" returns %s, at %s", humanize.Time(m.ReturnsAt), m.ReturnsAt.Format("2006-01-02 15:04"))
+	} else {
+		s += " has returned"
+	}
+	return s
+}
+
+func exportActiveMissionsToCsv(missions []*activeMission, path string) error {
+	action := fmt.Sprintf("exporting active missions to %s", path)
+	wrap := func(err error) error {
+		return errors.Wrap(err, "error "+action)
+	}
+
+	records := [][]string{{"ID", "Ship", "Type", "Level", "Status", "Launched at", "Returns at", "Seconds remaining", "Capacity", "Fuel"}}
+	for _, m := range missions {
+		records = append(records, []string{
+			m.Id,
+			m.ShipName,
+			m.DurationTypeName,
+			fmt.Sprint(m.Level),
+			m.StatusName,
+			m.LaunchedAtStr,
+			m.ReturnsAtStr,
+			fmt.Sprintf("%.0f", m.SecondsRemaining),
+			fmt.Sprint(m.Capacity),
+			m.FuelStr,
+		})
+	}
+
+	temp, err := writeCsvToTempfile(csvRecordSlice(records), filepath.Dir(path), tempfilePattern(path))
+	if err != nil {
+		return wrap(err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func exportActiveMissionsToIcs(playerId string, missions []*activeMission, path string) error {
+	var events []*calendarEvent
+	for _, m := range missions {
+		description := fmt.Sprintf("%s, %s, level %d, capacity %d\nLaunched at %s",
+			m.ShipName, m.DurationTypeName, m.Level, m.Capacity, m.LaunchedAtStr)
+		if m.FuelStr != "" {
+			description += "\nFuel: " + m.FuelStr
+		}
+		events = append(events, &calendarEvent{
+			Uid:         calendarUid("return-" + m.Id),
+			Start:       m.ReturnsAt,
+			Summary:     fmt.Sprintf("%s (%s) returns", m.ShipName, m.DurationTypeName),
+			Description: description,
+			Alarm:       true,
+		})
+	}
+	return exportEventsToIcs(fmt.Sprintf("EggLedger returns (%s)", playerId), calendarEventSlice(events), path)
+}
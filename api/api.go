@@ -54,3 +54,36 @@ func DecodeCompleteMissionPayload(payload []byte) (*ei.CompleteMissionResponse,
 	}
 	return msg, nil
 }
+
+func RequestArtifactsConfigurationRawPayloadWithContext(ctx context.Context, playerId string) ([]byte, error) {
+	req := &ei.ArtifactsConfigurationRequest{
+		Rinfo:         NewBasicRequestInfo(playerId),
+		ClientVersion: u32ptr(ClientVersion),
+	}
+	payload, err := RequestRawPayloadWithContext(ctx, "/ei_afx/config", req)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func DecodeArtifactsConfigurationPayload(payload []byte) (*ei.ArtifactsConfigurationResponse, error) {
+	msg := &ei.ArtifactsConfigurationResponse{}
+	err := DecodeAPIResponse(_apiPrefix+"/ei_afx/config", payload, msg, true)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func RequestArtifactsConfiguration(playerId string) (*ei.ArtifactsConfigurationResponse, error) {
+	return RequestArtifactsConfigurationWithContext(context.Background(), playerId)
+}
+
+func RequestArtifactsConfigurationWithContext(ctx context.Context, playerId string) (*ei.ArtifactsConfigurationResponse, error) {
+	payload, err := RequestArtifactsConfigurationRawPayloadWithContext(ctx, playerId)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeArtifactsConfigurationPayload(payload)
+}
@@ -128,6 +128,9 @@ func DecodeAPIResponse(apiUrl string, payload []byte, msg proto.Message, authent
 			return interpretUnmarshalError(err)
 		}
 	}
+	// Values added to the game after this build would otherwise be dropped
+	// into unknown fields.
+	ei.RecoverUnknownEnums(msg)
 	return nil
 }
 
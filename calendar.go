@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	_calendarProdId    = "-//EggLedger//EggLedger//EN"
+	_calendarUidDomain = "eggledger"
+)
+
+// calendarEvent is a VEVENT. Uid should be stable across exports so that
+// calendar apps update previously imported events rather than duplicating them.
+// End is optional; a zero End makes an instantaneous event. Alarm adds a
+// display alarm firing at the start of the event.
+type calendarEvent struct {
+	Uid         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Alarm       bool
+}
+
+// calendarEvents calls f on each event in turn, stopping at the first error.
+type calendarEvents func(f func(*calendarEvent) error) error
+
+// calendarEventSlice iterates over events already in memory.
+func calendarEventSlice(events []*calendarEvent) calendarEvents {
+	return func(f func(*calendarEvent) error) error {
+		for _, e := range events {
+			if err := f(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func exportEventsToIcs(calendarName string, events calendarEvents, path string) error {
+	action := fmt.Sprintf("exporting calendar to %s", path)
+	wrap := func(err error) error {
+		return errors.Wrap(err, "error "+action)
+	}
+
+	temp, err := writeIcsToTempfile(calendarName, events, filepath.Dir(path), tempfilePattern(path))
+	if err != nil {
+		return wrap(err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func writeIcsToTempfile(calendarName string, events calendarEvents, dir, pattern string) (temp string, err error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return
+	}
+	_ = os.Chmod(f.Name(), 0644)
+	temp = f.Name()
+	defer func() {
+		closeErr := f.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	w := bufio.NewWriter(f)
+	writeLine := func(line string) {
+		if err != nil {
+			return
+		}
+		_, err = w.WriteString(foldIcsLine(line) + "\r\n")
+	}
+	// DTSTAMP is the time the calendar was generated; use a single value for
+	// all events so that the file is internally consistent.
+	stamp := formatIcsTime(time.Now())
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:" + _calendarProdId)
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("METHOD:PUBLISH")
+	writeLine("X-WR-CALNAME:" + escapeIcsText(calendarName))
+	if err != nil {
+		return
+	}
+	err = events(func(e *calendarEvent) error {
+		writeLine("BEGIN:VEVENT")
+		writeLine("UID:" + e.Uid)
+		writeLine("DTSTAMP:" + stamp)
+		writeLine("DTSTART:" + formatIcsTime(e.Start))
+		if !e.End.IsZero() {
+			writeLine("DTEND:" + formatIcsTime(e.End))
+		}
+		writeLine("SUMMARY:" + escapeIcsText(e.Summary))
+		if e.Description != "" {
+			writeLine("DESCRIPTION:" + escapeIcsText(e.Description))
+		}
+		if e.Alarm {
+			writeLine("BEGIN:VALARM")
+			writeLine("ACTION:DISPLAY")
+			writeLine("DESCRIPTION:" + escapeIcsText(e.Summary))
+			writeLine("TRIGGER:PT0S")
+			writeLine("END:VALARM")
+		}
+		writeLine("END:VEVENT")
+		return err
+	})
+	writeLine("END:VCALENDAR")
+	if err != nil {
+		return
+	}
+	err = w.Flush()
+	return
+}
+
+// calendarUid returns a globally unique and stable UID for an object
+// identified by id.
+func calendarUid(id string) string {
+	return id + "@" + _calendarUidDomain
+}
+
+func formatIcsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeIcsText escapes a TEXT property value per RFC 5545 section 3.3.11.
+func escapeIcsText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// foldIcsLine folds a content line into lines no longer than 75 octets as
+// required by RFC 5545 section 3.1, taking care not to split multi-octet UTF-8
+// sequences.
+func foldIcsLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			// The leading space of a continuation line counts towards the limit.
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
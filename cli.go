@@ -0,0 +1,575 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+
+	"github.com/fanaticscripter/EggLedger/db"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// command is a subcommand run from the command line instead of launching the
+// UI, e.g. EggLedger lookup -player EI1234567890123456 -family gusset.
+type command struct {
+	usage       string
+	description string
+	run         func(fs *flag.FlagSet, args []string) error
+	// opensDatabase is set for commands that open the database themselves, if
+	// at all, since they must also run when the app can't open it, e.g. after
+	// a failed migration.
+	opensDatabase bool
+}
+
+var _commands = map[string]*command{
+	"check-db": {
+		usage:         "[-quarantine]",
+		description:   "check that stored missions and backups are intact",
+		run:           runCheckDbCommand,
+		opensDatabase: true,
+	},
+	"drift": {
+		usage:       "",
+		description: "list fields in stored payloads that are missing from ei.proto",
+		run:         runDriftCommand,
+	},
+	"droughts": {
+		usage:       "-player ID [-ship SHIP -duration TYPE] [-family FAMILY] [-rarity RARITY]",
+		description: "show how many missions it has been since matching items last dropped",
+		run:         runDroughtsCommand,
+	},
+	"plan": {
+		usage:       "-player ID [-family FAMILY] [-tier N] [-rarity RARITY] [-since YYYY-MM-DD] [-until YYYY-MM-DD] [-rank hour|fuel]",
+		description: "rank ships, durations and levels by how well they drop matching items",
+		run:         runPlanCommand,
+	},
+	"export-archive": {
+		usage:       "-out FILE [-player ID]",
+		description: "export stored missions and backups to an archive for use in another installation",
+		run:         runExportArchiveCommand,
+	},
+	"import-archive": {
+		usage:       "FILE",
+		description: "merge missions and backups from an archive into stored data",
+		run:         runImportArchiveCommand,
+	},
+	"import-missions": {
+		usage:       "-player ID FILE",
+		description: "import missions from an EggLedger .csv or .xlsx export or a JSON file",
+		run:         runImportMissionsCommand,
+	},
+	"forget-account": {
+		usage:       "-player ID [-archive FILE]",
+		description: "delete all stored data and exports of an account and remove it from known accounts (close the app first)",
+		run:         runForgetAccountCommand,
+	},
+	"restore-db": {
+		usage:         "[FILE]",
+		description:   "list automatic database backups, or restore one (close the app first)",
+		run:           runRestoreDbCommand,
+		opensDatabase: true,
+	},
+	"compact-db": {
+		usage:       "",
+		description: "recompress stored missions with a dictionary trained on them",
+		run:         runCompactDbCommand,
+	},
+	"migrate-db": {
+		usage:         "-to VERSION",
+		description:   "migrate the database to an older schema version for use by an older version (close the app first)",
+		run:           runMigrateDbCommand,
+		opensDatabase: true,
+	},
+	"lookup": {
+		usage:       "-player ID [-family FAMILY] [-tier N] [-rarity RARITY] [-since YYYY-MM-DD] [-until YYYY-MM-DD]",
+		description: "find the missions that dropped matching items",
+		run:         runLookupCommand,
+	},
+}
+
+// isCommand reports whether the command line arguments invoke a subcommand.
+// Anything else, e.g. arguments passed by the OS when launching an app bundle,
+// is ignored and the UI is launched as usual.
+func isCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if isHelpArg(args[0]) {
+		return true
+	}
+	_, ok := _commands[args[0]]
+	return ok
+}
+
+func isHelpArg(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// runCommand runs the subcommand and returns the exit status.
+func runCommand(args []string) int {
+	name := args[0]
+	if isHelpArg(name) {
+		printCommandsUsage()
+		return 0
+	}
+	cmd, ok := _commands[name]
+	if !ok {
+		printCommandsUsage()
+		return 2
+	}
+	// Storage and database are not initialized in these cases.
+	if _appIsInForbiddenDirectory {
+		fmt.Fprintln(os.Stderr, "error: app is in a forbidden directory, please move it into a separate folder")
+		return 1
+	}
+	if _appIsTranslocated {
+		fmt.Fprintln(os.Stderr, "error: app is translocated, please run the preflight script first")
+		return 1
+	}
+	if !cmd.opensDatabase {
+		if err := dataInit(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			return 1
+		}
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: EggLedger %s %s\n\n%s.\n\n", name, cmd.usage, capitalize(cmd.description))
+		fs.PrintDefaults()
+	}
+	if err := cmd.run(fs, args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func printCommandsUsage() {
+	var names []string
+	for name := range _commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "Usage: EggLedger [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\nLaunches the app when run without a command.\n\nCommands:")
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", name, _commands[name].description)
+	}
+	_ = w.Flush()
+	fmt.Fprintln(os.Stderr, "\nRun EggLedger [command] -h for help on a command.")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// requirePlayerId validates the -player flag, which most commands need.
+func requirePlayerId(playerId string) error {
+	if playerId == "" {
+		return errors.New("-player is required")
+	}
+	return nil
+}
+
+func runLookupCommand(fs *flag.FlagSet, args []string) error {
+	var q artifactQuery
+	playerId := fs.String("player", "", "player ID")
+	fs.StringVar(&q.Family, "family", "", "artifact family, e.g. ORNATE_GUSSET or gusset")
+	fs.IntVar(&q.Tier, "tier", 0, "tier, 1 to 4")
+	fs.StringVar(&q.Rarity, "rarity", "", "rarity: common, rare, epic or legendary")
+	fs.StringVar(&q.Since, "since", "", "only missions launched on or after this date")
+	fs.StringVar(&q.Until, "until", "", "only missions launched on or before this date")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requirePlayerId(*playerId); err != nil {
+		return err
+	}
+	drops, err := lookupArtifactDrops(*playerId, &q)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Mission\tShip\tType\tLevel\tReturned at\t#\tItem")
+	for _, d := range drops {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%s\n",
+			d.MissionId, d.Ship, d.Type, d.Level, d.ReturnedAt, d.DropIndex, d.Artifact)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d matching items\n", len(drops))
+	return nil
+}
+
+func runDroughtsCommand(fs *flag.FlagSet, args []string) error {
+	playerId := fs.String("player", "", "player ID")
+	shipArg := fs.String("ship", "", "ship, e.g. HENERPRISE or Henerprise; requires -duration")
+	durationArg := fs.String("duration", "", "duration type: short, standard or extended; requires -ship")
+	familyArg := fs.String("family", "", "artifact family, e.g. ORNATE_GUSSET or gusset")
+	rarityArg := fs.String("rarity", "", "rarity: rare, epic or legendary (default all three)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requirePlayerId(*playerId); err != nil {
+		return err
+	}
+
+	scope := droughtScope{All: true}
+	if *shipArg != "" || *durationArg != "" {
+		if *shipArg == "" || *durationArg == "" {
+			return errors.New("-ship and -duration must be used together")
+		}
+		ship, err := parseShip(*shipArg)
+		if err != nil {
+			return err
+		}
+		durationType, err := parseDurationType(*durationArg)
+		if err != nil {
+			return err
+		}
+		scope = droughtScope{shipDurationKey: shipDurationKey{ship, durationType}}
+	}
+	var family *ei.ArtifactSpec_Name
+	if *familyArg != "" {
+		f, err := parseArtifactFamily(*familyArg)
+		if err != nil {
+			return err
+		}
+		family = &f
+	}
+	rarities := []ei.ArtifactSpec_Rarity{ei.ArtifactSpec_LEGENDARY, ei.ArtifactSpec_EPIC, ei.ArtifactSpec_RARE}
+	if *rarityArg != "" {
+		rarity, err := parseArtifactRarity(*rarityArg)
+		if err != nil {
+			return err
+		}
+		rarities = []ei.ArtifactSpec_Rarity{rarity}
+	}
+
+	var stats []*droughtStats
+	for _, rarity := range rarities {
+		stats = append(stats, newDroughtStats(scope, dropTarget{Family: family, Rarity: rarity}))
+	}
+	considered := 0
+	err := db.IteratePlayerCompleteMissions(*playerId, func(m *ei.CompleteMissionResponse) error {
+		mission := newMission(m, nil)
+		if !scope.includes(mission) {
+			return nil
+		}
+		considered++
+		for _, s := range stats {
+			s.add(mission)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\n", scope.Display())
+	fmt.Fprintln(w, "Item\tDrops\tCurrent drought\tLongest drought\tCurrent streak\tLongest streak\tLast dropped at")
+	for _, s := range stats {
+		s.finish(now)
+		lastSeen := "never"
+		if !s.LastSeen.IsZero() {
+			lastSeen = s.LastSeen.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d missions, %.1f days\t%d missions, %.1f days\t%d\t%d\t%s\n",
+			s.Target.Display(), s.Matches,
+			s.CurrentDrought, s.CurrentDroughtDuration.Hours()/24,
+			s.LongestDrought, s.LongestDroughtDuration.Hours()/24,
+			s.CurrentStreak, s.LongestStreak, lastSeen)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d missions considered\n", considered)
+	return nil
+}
+
+func runPlanCommand(fs *flag.FlagSet, args []string) error {
+	var q artifactQuery
+	playerId := fs.String("player", "", "player ID")
+	fs.StringVar(&q.Family, "family", "", "artifact family, e.g. ORNATE_GUSSET or gusset")
+	fs.IntVar(&q.Tier, "tier", 0, "tier, 1 to 4")
+	fs.StringVar(&q.Rarity, "rarity", "", "rarity: common, rare, epic or legendary")
+	fs.StringVar(&q.Since, "since", "", "only consider missions launched on or after this date")
+	fs.StringVar(&q.Until, "until", "", "only consider missions launched on or before this date")
+	rank := fs.String("rank", "hour", "rank by drops per hour (hour) or by fuel of an egg type per drop (fuel:<egg>, e.g. fuel:dilithium)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requirePlayerId(*playerId); err != nil {
+		return err
+	}
+	plans, err := planMissionsForTarget(*playerId, &q, *rank)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Ship\tType\tLevel\tMissions\tDrops\tPer mission (95% CI)\tPer day (95% CI)\tFuel per drop")
+	for _, p := range plans {
+		fuelPerDrop := "-"
+		if p.FuelPerDropStr != "" {
+			fuelPerDrop = p.FuelPerDropStr
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.3f (%.3f-%.3f)\t%.3f (%.3f-%.3f)\t%s\n",
+			p.Ship, p.Type, p.Level, p.Missions, p.Drops,
+			p.DropsPerMission, p.DropsPerMissionLow, p.DropsPerMissionHigh,
+			p.DropsPerHour*24, p.DropsPerHourLow*24, p.DropsPerHourHigh*24,
+			fuelPerDrop)
+	}
+	return w.Flush()
+}
+
+func runDriftCommand(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	report, err := db.ScanSchemaDrift()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Message\tField\tWire type\tBackups\tMissions\tFirst seen\tLast seen")
+	for _, f := range report.Fields {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\t%s\n",
+			f.Message, f.Number, f.WireTypeName(), f.Backups, f.Missions,
+			unixToTime(f.FirstSeen).Format(time.RFC3339), unixToTime(f.LastSeen).Format(time.RFC3339))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d unknown fields in %d backups and %d missions", len(report.Fields), report.Backups, report.Missions)
+	if report.Undecodable > 0 {
+		fmt.Fprintf(os.Stderr, " (%d undecodable, skipped)", report.Undecodable)
+	}
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
+
+func runExportArchiveCommand(fs *flag.FlagSet, args []string) error {
+	out := fs.String("out", "", "archive file to create, e.g. EggLedger.zip")
+	playerId := fs.String("player", "", "only export data of this player ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("-out is required")
+	}
+	var playerIds []string
+	if *playerId != "" {
+		playerIds = []string{*playerId}
+	}
+	manifest, err := db.ExportArchive(*out, playerIds)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Skipped) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Player\tEntry\tError")
+		for _, e := range manifest.Skipped {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", e.PlayerId, e.Entry, e.Error)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "exported %d missions and %d backups to %s, skipped %d unreadable entries\n",
+		len(manifest.Missions), len(manifest.Backups), *out, len(manifest.Skipped))
+	return nil
+}
+
+func runForgetAccountCommand(fs *flag.FlagSet, args []string) error {
+	playerId := fs.String("player", "", "player ID")
+	archive := fs.String("archive", "", "archive file to export the account's data to before deleting it, e.g. EggLedger.zip")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requirePlayerId(*playerId); err != nil {
+		return err
+	}
+	result, err := forgetAccount(*playerId, *archive)
+	if result != nil {
+		for _, path := range result.RemovedFiles {
+			fmt.Println(path)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if result.Archive != "" {
+		fmt.Fprintf(os.Stderr, "archived data to %s\n", result.Archive)
+	}
+	fmt.Fprintf(os.Stderr, "deleted %d missions, %d backups and %d quarantined rows, removed %d exported files\n",
+		result.Missions, result.Backups, result.Quarantined, len(result.RemovedFiles))
+	return nil
+}
+
+func runImportArchiveCommand(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one archive file")
+	}
+	result, err := db.ImportArchive(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(result.Conflicts) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Player\tEntry\tConflict")
+		for _, c := range result.Conflicts {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", c.PlayerId, c.Entry, c.Reason)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "imported %d missions and %d backups, replaced %d imported missions with server versions, "+
+		"skipped %d missions and %d backups already stored, %d conflicts (stored data kept)\n",
+		result.MissionsImported, result.BackupsImported, result.MissionsReplaced, result.MissionsSkipped,
+		result.BackupsSkipped, len(result.Conflicts))
+	return nil
+}
+
+func runImportMissionsCommand(fs *flag.FlagSet, args []string) error {
+	playerId := fs.String("player", "", "player ID to import missions for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requirePlayerId(*playerId); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one file")
+	}
+	inserted, skipped, err := importMissions(*playerId, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "imported %d missions, skipped %d missions already stored\n", inserted, skipped)
+	return nil
+}
+
+func runRestoreDbCommand(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New("expected at most one backup file")
+	}
+	if fs.NArg() == 0 {
+		backups, err := db.ListDatabaseBackups(_dbPath)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Time\tReason\tSize\tFile")
+		for _, b := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				b.Time.Format("2006-01-02 15:04:05"), b.Reason, humanize.Bytes(uint64(b.Size)), b.Path)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d backups\n", len(backups))
+		return nil
+	}
+	if err := db.RestoreDatabaseBackup(_dbPath, fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "restored database from %s\n", fs.Arg(0))
+	return nil
+}
+
+func runCompactDbCommand(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	result, err := db.CompactMissionPayloads()
+	if err != nil {
+		return err
+	}
+	if result.DictionaryTrained {
+		fmt.Fprintln(os.Stderr, "trained compression dictionary")
+	}
+	fmt.Fprintf(os.Stderr, "recompressed %d missions, %s => %s\n",
+		result.Missions, humanize.Bytes(uint64(result.BytesBefore)), humanize.Bytes(uint64(result.BytesAfter)))
+	return nil
+}
+
+func runMigrateDbCommand(fs *flag.FlagSet, args []string) error {
+	to := fs.Int("to", -1, fmt.Sprintf("target schema version, at most %d", db.SchemaVersion()))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *to < 0 {
+		return errors.New("-to is required")
+	}
+	if err := dataInit(); err != nil {
+		return err
+	}
+	if err := db.MigrateDatabase(uint(*to)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "migrated database to schema version %d\n", *to)
+	return nil
+}
+
+func runCheckDbCommand(fs *flag.FlagSet, args []string) error {
+	quarantine := fs.Bool("quarantine", false,
+		"move corrupt missions and backups out of the way, so that missions are fetched again on the next sync")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := dataInit(); err != nil {
+		// Stored data can't be checked, but whether the file itself is intact
+		// tells whether restoring a backup is called for.
+		if fileErr := db.CheckDatabaseFile(_dbPath); fileErr != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", fileErr)
+		} else {
+			fmt.Fprintln(os.Stderr, "the database file passes SQLite's integrity check, but can't be opened")
+		}
+		return err
+	}
+	report, err := db.CheckIntegrity(*quarantine)
+	if err != nil {
+		return err
+	}
+	if len(report.Issues) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Table\tPlayer\tKey\tProblem")
+		for _, issue := range report.Issues {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Table, issue.PlayerId, issue.Key, issue.Problem)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "checked %d missions and %d backups, %d problems found",
+		report.Missions, report.Backups, len(report.Issues))
+	if *quarantine {
+		fmt.Fprintf(os.Stderr, ", %d rows quarantined", report.Quarantined)
+	}
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
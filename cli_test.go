@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/fanaticscripter/EggLedger/db"
+)
+
+// TestCommandsOnRefusedDatabase checks that databases the app refuses to open
+// are reported by migrate-db and check-db rather than crashing them, and that
+// restore-db can replace them.
+func TestCommandsOnRefusedDatabase(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		version uint
+		dirty   bool
+	}{
+		{"dirty", 8, true},
+		{"newer", db.SchemaVersion() + 1, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_dbPath = filepath.Join(t.TempDir(), "internal", "data.db")
+			if err := dataInit(); err != nil {
+				t.Fatal(err)
+			}
+			backup, err := db.BackupDatabase(db.DatabaseBackupReason_SYNC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Commands run in a fresh process, with the database not yet open.
+			db.CloseDB()
+			setSchemaVersion(t, test.version, test.dirty)
+
+			for _, args := range [][]string{
+				{"migrate-db", "-to", "8"},
+				{"check-db"},
+				{"lookup", "-player", "EI1"},
+			} {
+				if status := runCommand(args); status != 1 {
+					t.Errorf("%v exited with %d, expected 1", args, status)
+				}
+			}
+			if version, dirty := schemaVersion(t); version != test.version || dirty != test.dirty {
+				t.Errorf("schema version changed to %d (dirty %t)", version, dirty)
+			}
+
+			if status := runCommand([]string{"restore-db"}); status != 0 {
+				t.Errorf("restore-db exited with %d", status)
+			}
+			if status := runCommand([]string{"restore-db", backup.Path}); status != 0 {
+				t.Fatalf("restore-db %s exited with %d", backup.Path, status)
+			}
+			if version, dirty := schemaVersion(t); version != db.SchemaVersion() || dirty {
+				t.Errorf("schema version %d (dirty %t) after restoring, expected %d", version, dirty, db.SchemaVersion())
+			}
+			if status := runCommand([]string{"check-db"}); status != 0 {
+				t.Errorf("check-db exited with %d after restoring", status)
+			}
+		})
+	}
+}
+
+func setSchemaVersion(t *testing.T, version uint, dirty bool) {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", _dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`UPDATE schema_migrations SET version = ?, dirty = ?;`, version, dirty); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func schemaVersion(t *testing.T) (version uint, dirty bool) {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", _dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.QueryRow(`SELECT version, dirty FROM schema_migrations;`).Scan(&version, &dirty); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"time"
 
 	"github.com/pkg/errors"
@@ -16,11 +15,19 @@ import (
 
 var _dbPath string
 
-func dataInit() {
-	_dbPath = filepath.Join(_internalDir, "data.db")
+// Artifacts configuration rarely changes outside of game updates, so a cached
+// copy is good for a while.
+const _artifactsConfigurationMaxAge = 24 * time.Hour
+
+// dataInit opens the database at _dbPath. It isn't done in init, since some
+// commands must also run on databases the app refuses to open, see
+// command.opensDatabase.
+func dataInit() error {
 	if err := db.InitDB(_dbPath); err != nil {
-		log.Fatal(err)
+		return err
 	}
+	_storage.fillAccountMissionCounts(db.CountPlayerCompleteMissions)
+	return nil
 }
 
 func fetchFirstContactWithContext(ctx context.Context, playerId string) (*ei.EggIncFirstContactResponse, error) {
@@ -80,3 +87,39 @@ func fetchCompleteMissionWithContext(ctx context.Context, playerId string, missi
 	err = db.InsertCompleteMission(playerId, missionId, startTimestamp, payload)
 	return resp, err
 }
+
+// fetchArtifactsConfigurationWithContext returns the artifacts configuration,
+// from the local cache if it's recent enough, otherwise from the server. If the
+// server can't be reached, a stale cached configuration is used as a fallback
+// so that analyses work offline.
+func fetchArtifactsConfigurationWithContext(ctx context.Context, playerId string) (*ei.ArtifactsConfigurationResponse, error) {
+	action := "fetching artifacts configuration"
+	wrap := func(err error) error {
+		return errors.Wrap(err, "error "+action)
+	}
+	cached, fetchedAt, err := db.RetrieveArtifactsConfiguration(api.ClientVersion)
+	if err != nil {
+		// Treat as cache miss.
+		log.Error(err)
+	}
+	if cached != nil && time.Since(unixToTime(fetchedAt)) < _artifactsConfigurationMaxAge {
+		return cached, nil
+	}
+	payload, err := api.RequestArtifactsConfigurationRawPayloadWithContext(ctx, playerId)
+	if err == nil {
+		var config *ei.ArtifactsConfigurationResponse
+		config, err = api.DecodeArtifactsConfigurationPayload(payload)
+		if err == nil {
+			if err := db.InsertArtifactsConfiguration(api.ClientVersion, timeToUnix(time.Now()), payload); err != nil {
+				// Treat as non-fatal error.
+				log.Error(err)
+			}
+			return config, nil
+		}
+	}
+	if cached != nil {
+		log.Warnf("%s, using cached configuration from %s", wrap(err), unixToTime(fetchedAt))
+		return cached, nil
+	}
+	return nil, wrap(err)
+}
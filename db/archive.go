@@ -0,0 +1,431 @@
+package db
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/fanaticscripter/EggLedger/api"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// An archive is a zip file holding the raw (decompressed) payloads of stored
+// missions and backups, one file each, and a manifest describing them. It is
+// meant to move data between installations, independently of the database
+// schema.
+
+const (
+	_archiveFormat       = "EggLedger archive"
+	_archiveVersion      = 1
+	_archiveManifestPath = "manifest.json"
+)
+
+type ArchiveManifest struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// CreatedAt is a Unix timestamp.
+	CreatedAt float64 `json:"created_at"`
+	// SchemaVersion is the schema version of the exporting database, for
+	// information only.
+	SchemaVersion int               `json:"schema_version"`
+	Missions      []*ArchiveMission `json:"missions"`
+	Backups       []*ArchiveBackup  `json:"backups"`
+	// Skipped lists stored entries left out because they couldn't be read.
+	Skipped []*ArchiveSkippedEntry `json:"skipped,omitempty"`
+}
+
+type ArchiveMission struct {
+	PlayerId       string  `json:"player_id"`
+	MissionId      string  `json:"mission_id"`
+	StartTimestamp float64 `json:"start_timestamp"`
+	// Imported is true for missions imported from other sources than the game
+	// server.
+	Imported bool `json:"imported,omitempty"`
+	// Path is the path of the raw /ei_afx/complete_mission response payload
+	// in the archive, and SHA256 its hex encoded checksum.
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+type ArchiveBackup struct {
+	PlayerId   string  `json:"player_id"`
+	BackedUpAt float64 `json:"backed_up_at"`
+	// PayloadAuthenticated is true for payloads from the retired
+	// /ei/first_contact, which are wrapped in an AuthenticatedMessage.
+	PayloadAuthenticated bool   `json:"payload_authenticated"`
+	Path                 string `json:"path"`
+	SHA256               string `json:"sha256"`
+}
+
+type ArchiveSkippedEntry struct {
+	PlayerId string `json:"player_id"`
+	// Entry is e.g. "mission xxx" or "backup at 2006-01-02T15:04:05Z".
+	Entry string `json:"entry"`
+	Error string `json:"error"`
+}
+
+// ExportArchive writes the stored missions and backups of the players, or of
+// all players if playerIds is empty, to an archive at path. Entries with
+// corrupted payloads are left out, logged and listed in the manifest, so that
+// whatever can be saved is.
+func ExportArchive(path string, playerIds []string) (*ArchiveManifest, error) {
+	action := fmt.Sprintf("export archive to %s", path)
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	playerFilter := ""
+	var args []interface{}
+	if len(playerIds) > 0 {
+		playerFilter = "WHERE player_id IN (?" + strings.Repeat(", ?", len(playerIds)-1) + ")"
+		for _, id := range playerIds {
+			args = append(args, id)
+		}
+	}
+
+	tmpfile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	defer tmpfile.Close()
+	zw := zip.NewWriter(tmpfile)
+	writeEntry := func(name string, data []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	manifest := &ArchiveManifest{
+		Format:        _archiveFormat,
+		Version:       _archiveVersion,
+		CreatedAt:     float64(time.Now().Unix()),
+		SchemaVersion: _schemaVersion,
+	}
+	skip := func(playerId string, entry string, err error) {
+		log.Errorf("%s: %s for player %s: %s", action, entry, playerId, err)
+		manifest.Skipped = append(manifest.Skipped, &ArchiveSkippedEntry{
+			PlayerId: playerId,
+			Entry:    entry,
+			Error:    err.Error(),
+		})
+	}
+	err = transact(action, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT player_id, mission_id, start_timestamp, complete_payload, imported FROM mission
+			`+playerFilter+`
+			ORDER BY player_id, start_timestamp;`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			m := &ArchiveMission{}
+			var compressedPayload []byte
+			if err := rows.Scan(&m.PlayerId, &m.MissionId, &m.StartTimestamp, &compressedPayload, &m.Imported); err != nil {
+				return err
+			}
+			payload, err := decompress(compressedPayload)
+			if err != nil {
+				skip(m.PlayerId, "mission "+m.MissionId, err)
+				continue
+			}
+			m.Path = fmt.Sprintf("missions/%06d.pb", len(manifest.Missions)+1)
+			m.SHA256 = checksum(payload)
+			if err := writeEntry(m.Path, payload); err != nil {
+				return err
+			}
+			manifest.Missions = append(manifest.Missions, m)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(`SELECT player_id, backed_up_at, payload, payload_authenticated FROM backup
+			`+playerFilter+`
+			ORDER BY player_id, backed_up_at;`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			b := &ArchiveBackup{}
+			var compressedPayload []byte
+			if err := rows.Scan(&b.PlayerId, &b.BackedUpAt, &compressedPayload, &b.PayloadAuthenticated); err != nil {
+				return err
+			}
+			payload, err := decompress(compressedPayload)
+			if err != nil {
+				skip(b.PlayerId, backupEntry(b.BackedUpAt), err)
+				continue
+			}
+			b.Path = fmt.Sprintf("backups/%06d.pb", len(manifest.Backups)+1)
+			b.SHA256 = checksum(payload)
+			if err := writeEntry(b.Path, payload); err != nil {
+				return err
+			}
+			manifest.Backups = append(manifest.Backups, b)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if err := writeEntry(_archiveManifestPath, encoded); err != nil {
+		return nil, wrap(err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, wrap(err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		return nil, wrap(err)
+	}
+	if err := os.Rename(tmpfile.Name(), path); err != nil {
+		return nil, wrap(err)
+	}
+	return manifest, nil
+}
+
+type ArchiveImportResult struct {
+	MissionsImported int
+	// MissionsReplaced is the number of stored imported missions replaced by
+	// their server versions from the archive.
+	MissionsReplaced int
+	// MissionsSkipped is the number of missions already stored, identically,
+	// or from the server while the archive only has imported versions.
+	MissionsSkipped int
+	BackupsImported int
+	BackupsSkipped  int
+	// Conflicts are entries already stored with different data, or with
+	// data that can't be read; the stored data is kept.
+	Conflicts []*ArchiveConflict
+}
+
+type ArchiveConflict struct {
+	PlayerId string
+	// Entry is e.g. "mission xxx" or "backup at 2006-01-02T15:04:05Z".
+	Entry  string
+	Reason string
+}
+
+// ImportArchive merges the missions and backups in the archive at path into
+// the database. The archive is fully validated, and either everything is
+// imported or nothing is.
+func ImportArchive(path string) (*ArchiveImportResult, error) {
+	action := fmt.Sprintf("import archive %s", path)
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer zr.Close()
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	readEntry := func(name string, sha string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, errors.Errorf("%s missing from archive", name)
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "open %s", name)
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s", name)
+		}
+		if sha != "" && checksum(data) != sha {
+			return nil, errors.Errorf("%s: checksum mismatch", name)
+		}
+		return data, nil
+	}
+
+	data, err := readEntry(_archiveManifestPath, "")
+	if err != nil {
+		return nil, wrap(err)
+	}
+	var manifest ArchiveManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, wrap(errors.Wrap(err, "parse manifest"))
+	}
+	if manifest.Format != _archiveFormat {
+		return nil, wrap(errors.New("not an EggLedger archive"))
+	}
+	if manifest.Version > _archiveVersion {
+		return nil, wrap(errors.Errorf("archive version %d is newer than supported version %d, please update EggLedger",
+			manifest.Version, _archiveVersion))
+	}
+
+	result := &ArchiveImportResult{}
+	err = transact(action, func(tx *sql.Tx) error {
+		for _, m := range manifest.Missions {
+			payload, err := readEntry(m.Path, m.SHA256)
+			if err != nil {
+				return err
+			}
+			decoded, err := api.DecodeCompleteMissionPayload(payload)
+			if err != nil {
+				return errors.Wrapf(err, "mission %s for player %s", m.MissionId, m.PlayerId)
+			}
+			var startTimestamp float64
+			var compressedPayload []byte
+			var imported bool
+			row := tx.QueryRow(`SELECT start_timestamp, complete_payload, imported FROM mission
+				WHERE player_id = ? AND mission_id = ?;`,
+				m.PlayerId, m.MissionId)
+			err = row.Scan(&startTimestamp, &compressedPayload, &imported)
+			insert := func() error {
+				compressedPayload, codec, err := compressMission(payload)
+				if err != nil {
+					return err
+				}
+				_, err = tx.Exec(`INSERT INTO
+					mission(player_id, mission_id, start_timestamp, complete_payload, codec, imported)
+					VALUES (?, ?, ?, ?, ?, ?);`,
+					m.PlayerId, m.MissionId, m.StartTimestamp, compressedPayload, codec, m.Imported)
+				if err != nil {
+					return err
+				}
+				return insertDrops(tx, m.PlayerId, m.MissionId, m.StartTimestamp, decoded)
+			}
+			switch {
+			case err == sql.ErrNoRows:
+				if err := insert(); err != nil {
+					return err
+				}
+				result.MissionsImported++
+				continue
+			case err != nil:
+				return err
+			}
+			// The server version of a mission supersedes an imported one, as
+			// when fetching missions.
+			if imported != m.Imported {
+				if m.Imported {
+					result.MissionsSkipped++
+					continue
+				}
+				_, err := tx.Exec(`DELETE FROM mission
+					WHERE player_id = ? AND mission_id = ?;`,
+					m.PlayerId, m.MissionId)
+				if err != nil {
+					return err
+				}
+				if err := insert(); err != nil {
+					return err
+				}
+				result.MissionsReplaced++
+				continue
+			}
+			reason := conflictReason(compressedPayload, payload)
+			if reason == "" && startTimestamp != m.StartTimestamp {
+				reason = "different start time"
+			}
+			if reason != "" {
+				result.Conflicts = append(result.Conflicts, &ArchiveConflict{
+					PlayerId: m.PlayerId,
+					Entry:    "mission " + m.MissionId,
+					Reason:   reason,
+				})
+			} else {
+				result.MissionsSkipped++
+			}
+		}
+
+		for _, b := range manifest.Backups {
+			payload, err := readEntry(b.Path, b.SHA256)
+			if err != nil {
+				return err
+			}
+			entry := backupEntry(b.BackedUpAt)
+			if err := api.DecodeAPIResponse("/ei/first_contact", payload, &ei.EggIncFirstContactResponse{}, b.PayloadAuthenticated); err != nil {
+				return errors.Wrapf(err, "%s for player %s", entry, b.PlayerId)
+			}
+			var compressedPayload []byte
+			row := tx.QueryRow(`SELECT payload FROM backup
+				WHERE player_id = ? AND backed_up_at = ?;`,
+				b.PlayerId, b.BackedUpAt)
+			err = row.Scan(&compressedPayload)
+			switch {
+			case err == sql.ErrNoRows:
+				compressedPayload, err = compress(payload)
+				if err != nil {
+					return err
+				}
+				_, err = tx.Exec(`INSERT INTO
+					backup(player_id, backed_up_at, payload, payload_authenticated)
+					VALUES (?, ?, ?, ?);`,
+					b.PlayerId, b.BackedUpAt, compressedPayload, b.PayloadAuthenticated)
+				if err != nil {
+					return err
+				}
+				result.BackupsImported++
+				continue
+			case err != nil:
+				return err
+			}
+			reason := conflictReason(compressedPayload, payload)
+			if reason != "" {
+				result.Conflicts = append(result.Conflicts, &ArchiveConflict{
+					PlayerId: b.PlayerId,
+					Entry:    entry,
+					Reason:   reason,
+				})
+			} else {
+				result.BackupsSkipped++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// conflictReason compares a stored compressed payload to an imported payload,
+// and returns why they conflict, or "" if they don't. A stored payload that
+// can't be decompressed is a conflict too, rather than a reason to give up on
+// the whole archive; check-db can quarantine it.
+func conflictReason(storedCompressedPayload []byte, payload []byte) string {
+	stored, err := decompress(storedCompressedPayload)
+	if err != nil {
+		return "stored payload unreadable, run check-db"
+	}
+	if !bytes.Equal(stored, payload) {
+		return "different payload"
+	}
+	return ""
+}
+
+func backupEntry(backedUpAt float64) string {
+	return fmt.Sprintf("backup at %s", time.Unix(int64(backedUpAt), 0).UTC().Format(time.RFC3339))
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
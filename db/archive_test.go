@@ -0,0 +1,46 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestImportArchiveOverUnreadablePayload(t *testing.T) {
+	openTestDB(t)
+	insertTestMissions(t, "EI1", 5)
+	// Test missions predate artifacts, which check-db would object to.
+	if _, err := _db.Exec(`UPDATE mission SET start_timestamp = start_timestamp + 1e8;`); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "EggLedger.zip")
+	if _, err := ExportArchive(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := _db.Exec(`UPDATE mission SET complete_payload = 'corrupt'
+		WHERE rowid = (SELECT MIN(rowid) FROM mission);`); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ImportArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Reason != "stored payload unreadable, run check-db" {
+		t.Errorf("conflicts %+v, expected the unreadable mission", result.Conflicts)
+	}
+	if result.MissionsSkipped != 4 {
+		t.Errorf("%d missions skipped, expected 4", result.MissionsSkipped)
+	}
+
+	if _, err := CheckIntegrity(true); err != nil {
+		t.Fatal(err)
+	}
+	result, err = ImportArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MissionsImported != 1 || len(result.Conflicts) != 0 {
+		t.Errorf("imported %d missions with conflicts %+v after quarantining, expected 1 without",
+			result.MissionsImported, result.Conflicts)
+	}
+}
@@ -0,0 +1,356 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	_zstdLevel = zstd.SpeedBetterCompression
+	// A dictionary is trained once this many missions are stored; fewer
+	// missions aren't worth the trouble and make for a poor dictionary.
+	_minDictionarySamples = 200
+	// Payloads sampled evenly across stored missions make up the dictionary
+	// history, up to this size.
+	_maxDictionaryHistory = 64 << 10
+	_compactionBatchSize  = 200
+)
+
+type CompactionResult struct {
+	// DictionaryTrained is whether a dictionary was trained in this run.
+	DictionaryTrained bool
+	// Missions is the number of missions recompressed, and BytesBefore and
+	// BytesAfter are the total size of their payloads before and after.
+	Missions    int
+	BytesBefore int64
+	BytesAfter  int64
+}
+
+// _compactionLock is held while payloads are recompressed, so that the
+// background job, compact-db, migrate-db and deleting a player's data never
+// work on them at once.
+var _compactionLock sync.Mutex
+
+// CompactMissionPayloads trains a zstd dictionary on stored mission payloads if
+// there isn't one yet and there are enough missions, then recompresses gzipped
+// payloads with it in small batches. It does nothing before a dictionary can
+// be trained, and is cheap to call when there's nothing left to recompress.
+func CompactMissionPayloads() (*CompactionResult, error) {
+	_compactionLock.Lock()
+	defer _compactionLock.Unlock()
+	return compactMissionPayloads()
+}
+
+// StartBackgroundCompaction runs CompactMissionPayloads on its own goroutine,
+// unless it's already running, and logs the outcome. Batches are small, so
+// that syncs writing in the meantime are held up only briefly.
+func StartBackgroundCompaction() {
+	if !_compactionLock.TryLock() {
+		return
+	}
+	go func() {
+		defer _compactionLock.Unlock()
+		if _, err := compactMissionPayloads(); err != nil {
+			log.Error(err)
+		}
+	}()
+}
+
+func compactMissionPayloads() (*CompactionResult, error) {
+	result := &CompactionResult{}
+	_zstdLock.RLock()
+	trained := _zstdEncoder != nil
+	_zstdLock.RUnlock()
+	if !trained {
+		ok, err := trainCompressionDictionary()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return result, nil
+		}
+		result.DictionaryTrained = true
+	}
+
+	var afterRowid int64
+	for {
+		batch, err := recompressMissionBatch("mission", _codecGzip, afterRowid)
+		if err != nil {
+			return result, err
+		}
+		if batch.read == 0 {
+			break
+		}
+		afterRowid = batch.lastRowid
+		result.Missions += batch.n
+		result.BytesBefore += batch.before
+		result.BytesAfter += batch.after
+	}
+	if result.Missions > 0 {
+		log.Infof("recompressed %d mission payloads, %d => %d bytes",
+			result.Missions, result.BytesBefore, result.BytesAfter)
+	}
+	return result, nil
+}
+
+// trainCompressionDictionary trains and stores a dictionary, and reports
+// whether there were enough missions to do so.
+func trainCompressionDictionary() (bool, error) {
+	action := "train compression dictionary for mission payloads"
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	var history []byte
+	var samples [][]byte
+	err := transact(action, func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM mission WHERE NOT imported;`).Scan(&count); err != nil {
+			return err
+		}
+		if count < _minDictionarySamples {
+			return nil
+		}
+		// Imported payloads are reconstructed rather than what the server
+		// sends, so they are left out. Only about _minDictionarySamples
+		// payloads, spread evenly over time, are read, however many missions
+		// are stored.
+		step := count / _minDictionarySamples
+		rows, err := tx.Query(`SELECT complete_payload FROM (
+				SELECT complete_payload, ROW_NUMBER() OVER (ORDER BY start_timestamp) - 1 AS i
+				FROM mission
+				WHERE NOT imported
+			)
+			WHERE i % ? = 0
+			ORDER BY i;`, step)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		// Payloads alternately go into the history and the samples the
+		// entropy tables are built from, which must not be in the history.
+		for i := 0; rows.Next() && len(history) < _maxDictionaryHistory; i++ {
+			var compressedPayload []byte
+			if err := rows.Scan(&compressedPayload); err != nil {
+				return err
+			}
+			payload, err := decompress(compressedPayload)
+			if err != nil {
+				// Corrupt rows are check-db's business.
+				continue
+			}
+			if i%2 == 0 {
+				history = append(history, payload...)
+			} else {
+				samples = append(samples, payload)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(samples) == 0 {
+		return false, nil
+	}
+	if len(history) > _maxDictionaryHistory {
+		history = history[len(history)-_maxDictionaryHistory:]
+	}
+	err = transact(action, func(tx *sql.Tx) error {
+		var id uint32
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(id), 0) + 1 FROM compression_dictionary;`).Scan(&id); err != nil {
+			return err
+		}
+		dict, err := zstd.BuildDict(zstd.BuildDictOptions{
+			ID:       id,
+			Contents: samples,
+			History:  history,
+			Offsets:  [3]int{1, 4, 8},
+			Level:    _zstdLevel,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO compression_dictionary(id, created_at, dictionary) VALUES (?, ?, ?);`,
+			id, float64(time.Now().Unix()), dict)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := loadCompressionDictionaries(); err != nil {
+		return false, wrap(err)
+	}
+	log.Infof("trained compression dictionary on %d mission payloads", len(samples))
+	return true, nil
+}
+
+// recompressedBatch is the outcome of recompressMissionBatch.
+type recompressedBatch struct {
+	// read is the number of rows read, and lastRowid the rowid of the last
+	// one, to continue from.
+	read      int
+	lastRowid int64
+	// n is the number of payloads recompressed, and before and after their
+	// total size before and after.
+	n      int
+	before int64
+	after  int64
+}
+
+// recompressMissionBatch recompresses up to _compactionBatchSize payloads in
+// table, mission or quarantined_mission, stored with the given codec after
+// afterRowid, with the current codec; zstd payloads are converted back to
+// gzip. Payloads that can't be decompressed are logged and left alone, for
+// check-db to deal with. Rows are rewritten only if their payload is
+// unchanged since they were read, so that writes in the meantime are never
+// clobbered.
+func recompressMissionBatch(table string, fromCodec string, afterRowid int64) (batch recompressedBatch, err error) {
+	action := fmt.Sprintf("recompress payloads in %s", table)
+	type row struct {
+		rowid             int64
+		playerId          string
+		missionId         string
+		compressedPayload []byte
+	}
+	var rows []row
+	err = transact(action, func(tx *sql.Tx) error {
+		r, err := tx.Query(`SELECT rowid, player_id, mission_id, complete_payload FROM `+table+`
+			WHERE codec = ? AND complete_payload IS NOT NULL AND rowid > ?
+			ORDER BY rowid
+			LIMIT ?;`, fromCodec, afterRowid, _compactionBatchSize)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		for r.Next() {
+			var x row
+			if err := r.Scan(&x.rowid, &x.playerId, &x.missionId, &x.compressedPayload); err != nil {
+				return err
+			}
+			rows = append(rows, x)
+		}
+		return r.Err()
+	})
+	if err != nil || len(rows) == 0 {
+		return
+	}
+	batch.read = len(rows)
+	batch.lastRowid = rows[len(rows)-1].rowid
+
+	type update struct {
+		row
+		recompressed []byte
+		codec        string
+	}
+	var updates []update
+	for _, x := range rows {
+		payload, err := decompress(x.compressedPayload)
+		if err != nil {
+			log.Errorf("%s: skipping mission %s for player %s, run check-db: %s",
+				action, x.missionId, x.playerId, err)
+			continue
+		}
+		var recompressed []byte
+		var codec string
+		if fromCodec == _codecGzip {
+			recompressed, codec, err = compressMission(payload)
+		} else {
+			recompressed, err = compress(payload)
+			codec = _codecGzip
+		}
+		if err != nil {
+			return batch, errors.Wrap(err, action)
+		}
+		if codec == fromCodec {
+			// No dictionary to recompress with.
+			return recompressedBatch{}, nil
+		}
+		updates = append(updates, update{x, recompressed, codec})
+	}
+
+	err = transact(action, func(tx *sql.Tx) error {
+		for _, u := range updates {
+			res, err := tx.Exec(`UPDATE `+table+` SET complete_payload = ?, codec = ?
+				WHERE rowid = ? AND complete_payload = ?;`,
+				u.recompressed, u.codec, u.rowid, u.compressedPayload)
+			if err != nil {
+				return err
+			}
+			if affected, err := res.RowsAffected(); err != nil {
+				return err
+			} else if affected == 0 {
+				continue
+			}
+			batch.n++
+			batch.before += int64(len(u.compressedPayload))
+			batch.after += int64(len(u.recompressed))
+		}
+		return nil
+	})
+	return
+}
+
+// decompactMissionPayloads converts every zstd mission payload back to gzip,
+// quarantined ones included, for older versions of EggLedger.
+func decompactMissionPayloads() error {
+	_compactionLock.Lock()
+	defer _compactionLock.Unlock()
+	return decompactAllMissionPayloads()
+}
+
+// retrainCompressionDictionary replaces the stored dictionaries, whose history
+// holds raw payloads of the missions sampled, with one trained on the missions
+// stored now, e.g. after a player's data is deleted. Payloads are converted
+// back to gzip and the old dictionaries deleted, then, if enough missions are
+// left, a new dictionary is trained and they are recompressed with it. The
+// caller holds _compactionLock.
+func retrainCompressionDictionary() (*CompactionResult, error) {
+	action := "retrain compression dictionary for mission payloads"
+	_zstdLock.Lock()
+	trained := _zstdEncoder != nil
+	// Missions stored in the meantime are gzipped, so that none refer to
+	// the dictionaries about to be deleted.
+	_zstdEncoder = nil
+	_zstdLock.Unlock()
+	if !trained {
+		return &CompactionResult{}, nil
+	}
+
+	if err := decompactAllMissionPayloads(); err != nil {
+		return nil, err
+	}
+	err := transact(action, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM compression_dictionary;`)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := loadCompressionDictionaries(); err != nil {
+		return nil, errors.Wrap(err, action)
+	}
+	return compactMissionPayloads()
+}
+
+func decompactAllMissionPayloads() error {
+	for _, table := range []string{"mission", "quarantined_mission"} {
+		var afterRowid int64
+		for {
+			batch, err := recompressMissionBatch(table, _codecZstd, afterRowid)
+			if err != nil {
+				return err
+			}
+			if batch.read == 0 {
+				break
+			}
+			afterRowid = batch.lastRowid
+		}
+	}
+	return nil
+}
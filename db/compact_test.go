@@ -0,0 +1,351 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// openTestDB opens a fresh database in a temporary directory as the package
+// database, closing it when the test is done.
+func openTestDB(tb testing.TB) string {
+	tb.Helper()
+	dbPath := filepath.Join(tb.TempDir(), "data.db")
+	if err := openDB(dbPath); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(CloseDB)
+	return dbPath
+}
+
+// testMission generates the i-th of a series of missions one launch apart,
+// with a few drops, as the server would send them.
+func testMission(r *rand.Rand, playerId string, i int) *ei.CompleteMissionResponse {
+	ship := ei.MissionInfo_Spaceship(r.Intn(int(ei.MissionInfo_HENERPRISE) + 1))
+	durationType := ei.MissionInfo_DurationType(r.Intn(3))
+	level := uint32(r.Intn(9))
+	durationSeconds := float64(3600 * (1 + r.Intn(96)))
+	capacity := uint32(3 + r.Intn(40))
+	startTimestamp := 1.6e9 + float64(i)*3600
+	identifier := fmt.Sprintf("%016x", r.Uint64())
+	m := &ei.CompleteMissionResponse{
+		Success: proto.Bool(true),
+		Info: &ei.MissionInfo{
+			Ship:             &ship,
+			Status:           ei.MissionInfo_ARCHIVED.Enum(),
+			DurationType:     &durationType,
+			Level:            &level,
+			DurationSeconds:  &durationSeconds,
+			Capacity:         &capacity,
+			StartTimeDerived: &startTimestamp,
+			Identifier:       &identifier,
+		},
+		EiUserId: &playerId,
+	}
+	for j := 0; j < int(capacity); j++ {
+		name := ei.ArtifactSpec_Name(r.Intn(int(ei.ArtifactSpec_PUZZLE_CUBE) + 1))
+		level := ei.ArtifactSpec_Level(r.Intn(4))
+		rarity := ei.ArtifactSpec_Rarity(r.Intn(4))
+		serverId := fmt.Sprintf("%d", r.Int63())
+		m.Artifacts = append(m.Artifacts, &ei.CompleteMissionResponse_SecureArtifactSpec{
+			Spec:     &ei.ArtifactSpec{Name: &name, Level: &level, Rarity: &rarity},
+			ServerId: &serverId,
+		})
+	}
+	return m
+}
+
+// insertTestMissions stores n generated missions for the player, compressed
+// with the current codec, in one transaction.
+func insertTestMissions(tb testing.TB, playerId string, n int) {
+	tb.Helper()
+	r := rand.New(rand.NewSource(int64(n)))
+	err := transact("insert test missions", func(tx *sql.Tx) error {
+		for i := 0; i < n; i++ {
+			m := testMission(r, playerId, i)
+			message, err := proto.Marshal(m)
+			if err != nil {
+				return err
+			}
+			payload, err := proto.Marshal(&ei.AuthenticatedMessage{Message: message})
+			if err != nil {
+				return err
+			}
+			compressedPayload, codec, err := compressMission(payload)
+			if err != nil {
+				return err
+			}
+			missionId := m.GetInfo().GetIdentifier()
+			startTimestamp := m.GetInfo().GetStartTimeDerived()
+			if _, err := tx.Exec(`INSERT INTO
+				mission(player_id, mission_id, start_timestamp, complete_payload, codec)
+				VALUES (?, ?, ?, ?, ?);`,
+				playerId, missionId, startTimestamp, compressedPayload, codec); err != nil {
+				return err
+			}
+			if err := insertDrops(tx, playerId, missionId, startTimestamp, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestCompactionSkipsCorruptPayloads(t *testing.T) {
+	openTestDB(t)
+	insertTestMissions(t, "EI1", 2*_minDictionarySamples)
+	corrupt := []byte("not a payload")
+	if _, err := _db.Exec(`UPDATE mission SET complete_payload = ?
+		WHERE rowid = (SELECT MIN(rowid) FROM mission);`, corrupt); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := CompactMissionPayloads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.DictionaryTrained {
+		t.Error("no dictionary trained")
+	}
+	if expected := 2*_minDictionarySamples - 1; result.Missions != expected {
+		t.Errorf("recompressed %d missions, expected %d", result.Missions, expected)
+	}
+	var payload []byte
+	var codec string
+	if err := _db.QueryRow(`SELECT complete_payload, codec FROM mission
+		WHERE rowid = (SELECT MIN(rowid) FROM mission);`).Scan(&payload, &codec); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload, corrupt) || codec != _codecGzip {
+		t.Errorf("corrupt mission rewritten to %s %q", codec, payload)
+	}
+
+	result, err = CompactMissionPayloads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Missions != 0 || result.DictionaryTrained {
+		t.Errorf("compacted again: %+v", result)
+	}
+}
+
+func TestDecompactQuarantinedMissions(t *testing.T) {
+	dbPath := openTestDB(t)
+	insertTestMissions(t, "EI1", _minDictionarySamples)
+	if _, err := CompactMissionPayloads(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := _db.Exec(`INSERT INTO
+		quarantined_mission(player_id, mission_id, start_timestamp, complete_payload, codec, problem, quarantined_at)
+		SELECT player_id, mission_id, start_timestamp, complete_payload, codec, 'test', 0 FROM mission
+		WHERE rowid = (SELECT MIN(rowid) FROM mission);`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MigrateDatabase(8); err != nil {
+		t.Fatal(err)
+	}
+	withTestConn(t, dbPath, func(conn *sql.DB) {
+		for _, table := range []string{"mission", "quarantined_mission"} {
+			rows, err := conn.Query(`SELECT complete_payload FROM ` + table + `;`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := 0
+			for rows.Next() {
+				var compressedPayload []byte
+				if err := rows.Scan(&compressedPayload); err != nil {
+					t.Fatal(err)
+				}
+				if err := gunzip(compressedPayload); err != nil {
+					t.Errorf("%s payload not gzipped: %s", table, err)
+				}
+				n++
+			}
+			rows.Close()
+			if n == 0 {
+				t.Errorf("no payloads in %s", table)
+			}
+		}
+	})
+}
+
+func gunzip(compressedPayload []byte) error {
+	r, err := gzip.NewReader(bytes.NewReader(compressedPayload))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// BenchmarkMissionPayloads reports the database size per mission and the time
+// to decode a stored mission, before and after compaction.
+func BenchmarkMissionPayloads(b *testing.B) {
+	const missions = 2000
+	dbPath := openTestDB(b)
+	insertTestMissions(b, "EI1", missions)
+
+	bench := func(b *testing.B) {
+		if _, err := _db.Exec(`VACUUM;`); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := _db.Exec(`PRAGMA wal_checkpoint(TRUNCATE);`); err != nil {
+			b.Fatal(err)
+		}
+		info, err := os.Stat(dbPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var payloadBytes int64
+		var payloads [][]byte
+		rows, err := _db.Query(`SELECT complete_payload FROM mission ORDER BY start_timestamp;`)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for rows.Next() {
+			var compressedPayload []byte
+			if err := rows.Scan(&compressedPayload); err != nil {
+				b.Fatal(err)
+			}
+			payloadBytes += int64(len(compressedPayload))
+			payloads = append(payloads, compressedPayload)
+		}
+		rows.Close()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := decodeStoredMission(0, payloads[i%len(payloads)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(info.Size())/missions, "db-bytes/mission")
+		b.ReportMetric(float64(payloadBytes)/missions, "payload-bytes/mission")
+	}
+
+	b.Run("gzip", bench)
+	if _, err := CompactMissionPayloads(); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("zstd", bench)
+}
+
+func TestDeletePlayerDataRetrainsDictionary(t *testing.T) {
+	const forgotten, kept = "EI1111111111111111", "EI2222222222222222"
+	openTestDB(t)
+	insertTestMissions(t, forgotten, _minDictionarySamples+50)
+	insertTestMissions(t, kept, _minDictionarySamples+100)
+	if _, err := CompactMissionPayloads(); err != nil {
+		t.Fatal(err)
+	}
+	if !dictionariesContain(t, forgotten) {
+		t.Fatalf("no payloads of %s in the dictionary to begin with", forgotten)
+	}
+
+	if _, err := DeletePlayerData(forgotten); err != nil {
+		t.Fatal(err)
+	}
+	if dictionariesContain(t, forgotten) {
+		t.Errorf("payloads of %s left in dictionaries", forgotten)
+	}
+	if n := countMissions(t, kept, _codecZstd); n != _minDictionarySamples+100 {
+		t.Errorf("%d missions recompressed with the new dictionary", n)
+	}
+	checkPayloadsDecode(t)
+
+	// Too few missions are left to train a dictionary on.
+	if _, err := DeletePlayerData(kept); err != nil {
+		t.Fatal(err)
+	}
+	insertTestMissions(t, forgotten, 10)
+	if _, err := DeletePlayerData(forgotten); err != nil {
+		t.Fatal(err)
+	}
+	var dictionaries int
+	if err := _db.QueryRow(`SELECT COUNT(*) FROM compression_dictionary;`).Scan(&dictionaries); err != nil {
+		t.Fatal(err)
+	}
+	if dictionaries != 0 {
+		t.Errorf("%d dictionaries left without missions", dictionaries)
+	}
+}
+
+func dictionariesContain(t *testing.T, s string) bool {
+	t.Helper()
+	rows, err := _db.Query(`SELECT dictionary FROM compression_dictionary;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	found := false
+	for rows.Next() {
+		var dict []byte
+		if err := rows.Scan(&dict); err != nil {
+			t.Fatal(err)
+		}
+		found = found || bytes.Contains(dict, []byte(s))
+	}
+	return found
+}
+
+func countMissions(t *testing.T, playerId string, codec string) int {
+	t.Helper()
+	var n int
+	if err := _db.QueryRow(`SELECT COUNT(*) FROM mission WHERE player_id = ? AND codec = ?;`,
+		playerId, codec).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func checkPayloadsDecode(t *testing.T) {
+	t.Helper()
+	rows, err := _db.Query(`SELECT mission_id, complete_payload FROM mission;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var missionId string
+		var compressedPayload []byte
+		if err := rows.Scan(&missionId, &compressedPayload); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decompress(compressedPayload); err != nil {
+			t.Errorf("mission %s: %s", missionId, err)
+		}
+	}
+}
+
+func TestDeletePlayerDataDuringCompaction(t *testing.T) {
+	openTestDB(t)
+	insertTestMissions(t, "EI1", 2*_minDictionarySamples)
+	insertTestMissions(t, "EI2", _minDictionarySamples)
+
+	StartBackgroundCompaction()
+	deleted := make(chan error)
+	go func() {
+		_, err := DeletePlayerData("EI2")
+		deleted <- err
+	}()
+	insertTestMissions(t, "EI3", 50)
+	if err := <-deleted; err != nil {
+		t.Fatal(err)
+	}
+	_compactionLock.Lock()
+	_compactionLock.Unlock()
+	checkPayloadsDecode(t)
+}
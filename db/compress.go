@@ -3,6 +3,30 @@ package db
 import (
 	"bytes"
 	"compress/gzip"
+	"database/sql"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Codecs of mission payloads, as stored in mission.codec. Other payloads are
+// always gzipped.
+const (
+	_codecGzip = "gzip"
+	_codecZstd = "zstd"
+)
+
+var _zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+var (
+	_zstdLock sync.RWMutex
+	// _zstdEncoder compresses with the latest dictionary, and is nil until a
+	// dictionary has been trained.
+	_zstdEncoder *zstd.Encoder
+	// _zstdDecoder knows every stored dictionary, since zstd frames name the
+	// dictionary they were compressed with.
+	_zstdDecoder *zstd.Decoder
 )
 
 func compress(in []byte) ([]byte, error) {
@@ -18,7 +42,32 @@ func compress(in []byte) ([]byte, error) {
 	return out.Bytes(), nil
 }
 
+// compressMission compresses a mission payload with zstd and the latest
+// dictionary, or with gzip until there are enough stored missions to train
+// one. The codec is returned for mission.codec.
+func compressMission(in []byte) ([]byte, string, error) {
+	_zstdLock.RLock()
+	encoder := _zstdEncoder
+	_zstdLock.RUnlock()
+	if encoder == nil {
+		out, err := compress(in)
+		return out, _codecGzip, err
+	}
+	return encoder.EncodeAll(in, nil), _codecZstd, nil
+}
+
+// decompress decompresses gzip or zstd payloads, telling them apart by their
+// magic numbers.
 func decompress(in []byte) ([]byte, error) {
+	if bytes.HasPrefix(in, _zstdMagic) {
+		_zstdLock.RLock()
+		decoder := _zstdDecoder
+		_zstdLock.RUnlock()
+		if decoder == nil {
+			return nil, errors.New("zstd decoder not initialized")
+		}
+		return decoder.DecodeAll(in, nil)
+	}
 	r, err := gzip.NewReader(bytes.NewReader(in))
 	if err != nil {
 		return nil, err
@@ -30,3 +79,52 @@ func decompress(in []byte) ([]byte, error) {
 	}
 	return out.Bytes(), nil
 }
+
+// loadCompressionDictionaries sets up the zstd encoder and decoder with the
+// dictionaries stored in the database.
+func loadCompressionDictionaries() error {
+	action := "load compression dictionaries from database"
+	var dicts [][]byte
+	err := transact(action, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT dictionary FROM compression_dictionary ORDER BY id;`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var dict []byte
+			if err := rows.Scan(&dict); err != nil {
+				return err
+			}
+			dicts = append(dicts, dict)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	// Missions are decoded on one goroutine per CPU, see decodeMissionRows.
+	decoder, err := zstd.NewReader(nil,
+		zstd.WithDecoderDicts(dicts...), zstd.WithDecoderConcurrency(0))
+	if err != nil {
+		return errors.Wrap(err, action)
+	}
+	var encoder *zstd.Encoder
+	if len(dicts) > 0 {
+		encoder, err = zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(_zstdLevel), zstd.WithEncoderDict(dicts[len(dicts)-1]))
+		if err != nil {
+			decoder.Close()
+			return errors.Wrap(err, action)
+		}
+	}
+
+	// The previous encoder and decoder may still be in use, and aren't closed;
+	// they only hold goroutines when used for streams, which they aren't.
+	_zstdLock.Lock()
+	defer _zstdLock.Unlock()
+	_zstdDecoder = decoder
+	_zstdEncoder = encoder
+	return nil
+}
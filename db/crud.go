@@ -51,19 +51,30 @@ func InsertBackup(playerId string, timestamp float64, payload []byte, minimumTim
 
 func InsertCompleteMission(playerId string, missionId string, startTimestamp float64, completePayload []byte) error {
 	action := fmt.Sprintf("insert mission %s for player %s into database", missionId, playerId)
-	compressedPayload, err := compress(completePayload)
+	m, err := api.DecodeCompleteMissionPayload(completePayload)
+	if err != nil {
+		return errors.Wrap(err, action)
+	}
+	compressedPayload, codec, err := compressMission(completePayload)
 	if err != nil {
 		return errors.Wrap(err, action)
 	}
 	return transact(action, func(tx *sql.Tx) error {
-		_, err := tx.Exec(`INSERT INTO
-			mission(player_id, mission_id, start_timestamp, complete_payload)
-			VALUES (?, ?, ?, ?);`,
-			playerId, missionId, startTimestamp, compressedPayload)
+		// The server version of the mission supersedes an imported one.
+		_, err := tx.Exec(`DELETE FROM mission
+			WHERE player_id = ? AND mission_id = ? AND imported;`,
+			playerId, missionId)
 		if err != nil {
 			return err
 		}
-		return nil
+		_, err = tx.Exec(`INSERT INTO
+			mission(player_id, mission_id, start_timestamp, complete_payload, codec)
+			VALUES (?, ?, ?, ?, ?);`,
+			playerId, missionId, startTimestamp, compressedPayload, codec)
+		if err != nil {
+			return err
+		}
+		return insertDrops(tx, playerId, missionId, startTimestamp, m)
 	})
 }
 
@@ -91,27 +102,34 @@ func RetrieveCompleteMission(playerId string, missionId string) (*ei.CompleteMis
 	if compressedPayload == nil {
 		return nil, nil
 	}
-	completePayload, err := decompress(compressedPayload)
-	if err != nil {
-		return nil, errors.Wrap(err, action)
-	}
-	m, err := api.DecodeCompleteMissionPayload(completePayload)
+	m, err := decodeStoredMission(startTimestamp, compressedPayload)
 	if err != nil {
 		return nil, errors.Wrap(err, action)
 	}
-	// /ei_afx/complete_mission response leaves out start_time_derived, so we
-	// have to manually attach it.
-	m.Info.StartTimeDerived = &startTimestamp
 	return m, nil
 }
 
 // RetrievePlayerCompleteMissions retrieves stored completed missions for a
 // player, in chronological order.
 func RetrievePlayerCompleteMissions(playerId string) ([]*ei.CompleteMissionResponse, error) {
+	var missions []*ei.CompleteMissionResponse
+	err := IteratePlayerCompleteMissions(playerId, func(m *ei.CompleteMissionResponse) error {
+		missions = append(missions, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return missions, nil
+}
+
+// IteratePlayerCompleteMissions calls f with each stored completed mission of a
+// player, in chronological order, stopping at the first error, which is
+// returned as is. Missions are decoded in parallel while reading, and memory
+// use doesn't grow with the number of missions as long as f doesn't keep them.
+func IteratePlayerCompleteMissions(playerId string, f func(*ei.CompleteMissionResponse) error) error {
 	action := fmt.Sprintf("retrieve complete missions for player %s from database", playerId)
-	var count int
-	var startTimestamps []float64
-	var compressedPayloads [][]byte
+	var callbackErr error
 	err := transact(action, func(tx *sql.Tx) error {
 		rows, err := tx.Query(`SELECT start_timestamp, complete_payload FROM mission
 			WHERE player_id = ?
@@ -120,48 +138,29 @@ func RetrievePlayerCompleteMissions(playerId string) ([]*ei.CompleteMissionRespo
 			return err
 		}
 		defer rows.Close()
-		for rows.Next() {
-			var startTimestamp float64
-			var compressedPayload []byte
-			if err := rows.Scan(&startTimestamp, &compressedPayload); err != nil {
+		return decodeMissionRows(rows, func(m *ei.CompleteMissionResponse) error {
+			if err := f(m); err != nil {
+				callbackErr = err
 				return err
 			}
-			count++
-			startTimestamps = append(startTimestamps, startTimestamp)
-			compressedPayloads = append(compressedPayloads, compressedPayload)
-		}
-		if err := rows.Err(); err != nil {
-			return err
-		}
-		return nil
+			return nil
+		})
 	})
-	if err != nil {
-		return nil, err
-	}
-	var missions []*ei.CompleteMissionResponse
-	for i := 0; i < count; i++ {
-		completePayload, err := decompress(compressedPayloads[i])
-		if err != nil {
-			return nil, errors.Wrap(err, action)
-		}
-		m, err := api.DecodeCompleteMissionPayload(completePayload)
-		if err != nil {
-			return nil, errors.Wrap(err, action)
-		}
-		m.Info.StartTimeDerived = &startTimestamps[i]
-		missions = append(missions, m)
+	if callbackErr != nil {
+		return callbackErr
 	}
-	return missions, nil
+	return err
 }
 
 // RetrievePlayerCompleteMissionIds retrieves IDs of stored completed missions
-// for a player, in chronological order.
+// for a player, in chronological order. Imported missions are left out, so that
+// they are fetched from the server if it still has them.
 func RetrievePlayerCompleteMissionIds(playerId string) ([]string, error) {
 	action := fmt.Sprintf("retrieve complete mission ids for player %s from database", playerId)
 	var missionIds []string
 	err := transact(action, func(tx *sql.Tx) error {
 		rows, err := tx.Query(`SELECT mission_id FROM mission
-			WHERE player_id = ?
+			WHERE player_id = ? AND NOT imported
 			ORDER BY start_timestamp;`, playerId)
 		if err != nil {
 			return err
@@ -185,6 +184,82 @@ func RetrievePlayerCompleteMissionIds(playerId string) ([]string, error) {
 	return missionIds, nil
 }
 
+// CountPlayerCompleteMissions counts stored completed missions for a player,
+// including imported ones.
+func CountPlayerCompleteMissions(playerId string) (int, error) {
+	action := fmt.Sprintf("count complete missions for player %s in database", playerId)
+	var count int
+	err := transact(action, func(tx *sql.Tx) error {
+		return tx.QueryRow(`SELECT COUNT(*) FROM mission WHERE player_id = ?;`, playerId).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// InsertArtifactsConfiguration caches a raw /ei_afx/config response payload.
+// Older entries for the same client version are discarded.
+func InsertArtifactsConfiguration(clientVersion uint32, timestamp float64, payload []byte) error {
+	action := fmt.Sprintf("insert artifacts configuration for client version %d into database", clientVersion)
+	compressedPayload, err := compress(payload)
+	if err != nil {
+		return errors.Wrap(err, action)
+	}
+	return transact(action, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM artifacts_configuration WHERE client_version = ?;`, clientVersion)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO
+			artifacts_configuration(client_version, fetched_at, payload)
+			VALUES (?, ?, ?);`,
+			clientVersion, timestamp, compressedPayload)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// RetrieveArtifactsConfiguration returns the latest cached artifacts
+// configuration for the client version and the time it was fetched, or nil if
+// not found.
+func RetrieveArtifactsConfiguration(clientVersion uint32) (*ei.ArtifactsConfigurationResponse, float64, error) {
+	action := fmt.Sprintf("retrieve artifacts configuration for client version %d from database", clientVersion)
+	var fetchedAt float64
+	var compressedPayload []byte
+	err := transact(action, func(tx *sql.Tx) error {
+		row := tx.QueryRow(`SELECT fetched_at, payload FROM artifacts_configuration
+			WHERE client_version = ?
+			ORDER BY fetched_at DESC LIMIT 1;`,
+			clientVersion)
+		err := row.Scan(&fetchedAt, &compressedPayload)
+		switch {
+		case err == sql.ErrNoRows:
+			// No cached configuration
+		case err != nil:
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if compressedPayload == nil {
+		return nil, 0, nil
+	}
+	payload, err := decompress(compressedPayload)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, action)
+	}
+	config, err := api.DecodeArtifactsConfigurationPayload(payload)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, action)
+	}
+	return config, fetchedAt, nil
+}
+
 func transact(description string, txFunc func(*sql.Tx) error) (err error) {
 	tx, err := _db.Begin()
 	if err != nil {
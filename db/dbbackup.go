@@ -0,0 +1,435 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DatabaseBackupReason_MIGRATION = "migration"
+	DatabaseBackupReason_SYNC      = "sync"
+	DatabaseBackupReason_RESTORE   = "restore"
+
+	_databaseBackupDir        = "db-backups"
+	_databaseBackupTimeFormat = "20060102_150405"
+)
+
+// _databaseBackupRetention is the number of backups kept for each reason;
+// older ones are removed whenever a new one is taken.
+var _databaseBackupRetention = map[string]int{
+	DatabaseBackupReason_MIGRATION: 3,
+	DatabaseBackupReason_SYNC:      5,
+	DatabaseBackupReason_RESTORE:   3,
+}
+
+// DatabaseBackup is a copy of the whole database file, as opposed to the game
+// backups stored in the backup table.
+type DatabaseBackup struct {
+	Path   string
+	Time   time.Time
+	Reason string
+	Size   int64
+
+	// seq orders backups taken within the same second.
+	seq int
+}
+
+// BackupDatabase takes a consistent copy of the open database with VACUUM INTO,
+// which doesn't block writers, and removes old backups for the same reason.
+func BackupDatabase(reason string) (*DatabaseBackup, error) {
+	return backupDatabase(_db, _dbPath, reason)
+}
+
+func backupDatabase(conn *sql.DB, dbPath string, reason string) (*DatabaseBackup, error) {
+	action := fmt.Sprintf("back up database %#v", dbPath)
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	dir := databaseBackupDir(dbPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, wrap(err)
+	}
+	now := time.Now()
+	path, tmpPath, err := reserveDatabaseBackupPath(dbPath, now, reason)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	// VACUUM INTO writes to the reserved temporary file, which is empty, so
+	// that a half-written backup is never mistaken for a complete one.
+	if _, err := conn.Exec(`VACUUM INTO ?;`, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, wrap(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, wrap(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	log.Infof("backed up database to %s", path)
+
+	if err := rotateDatabaseBackups(dbPath, reason); err != nil {
+		log.Error(err)
+	}
+	return &DatabaseBackup{
+		Path:   path,
+		Time:   now,
+		Reason: reason,
+		Size:   info.Size(),
+	}, nil
+}
+
+// reserveDatabaseBackupPath returns an unused path for a backup taken at t, and
+// the temporary file to write it to, which it creates. Backups taken within the
+// same second get a sequence number, e.g. data.20060102_150405-2.sync.db.
+func reserveDatabaseBackupPath(dbPath string, t time.Time, reason string) (path string, tmpPath string, err error) {
+	stamp := t.Format(_databaseBackupTimeFormat)
+	for seq := 1; ; seq++ {
+		name := stamp
+		if seq > 1 {
+			name = fmt.Sprintf("%s-%d", stamp, seq)
+		}
+		path = filepath.Join(databaseBackupDir(dbPath), fmt.Sprintf("%s.%s.%s.db", databaseBackupPrefix(dbPath), name, reason))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return "", "", err
+		}
+		// Another backup may be in progress, or may have been interrupted
+		// and left its temporary file behind.
+		tmpPath = path + ".tmp"
+		f, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if err := f.Close(); err != nil {
+			os.Remove(tmpPath)
+			return "", "", err
+		}
+		return path, tmpPath, nil
+	}
+}
+
+// ListDatabaseBackups lists backups of the database at dbPath, newest first.
+// The database needn't be open.
+func ListDatabaseBackups(dbPath string) ([]*DatabaseBackup, error) {
+	return listDatabaseBackups(dbPath)
+}
+
+func listDatabaseBackups(dbPath string) ([]*DatabaseBackup, error) {
+	action := "list database backups"
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	dir := databaseBackupDir(dbPath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, wrap(err)
+	}
+	prefix := databaseBackupPrefix(dbPath) + "."
+	var backups []*DatabaseBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".db") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".db"), ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		stamp, seq := parts[0], 1
+		if i := strings.IndexByte(stamp, '-'); i >= 0 {
+			n, err := strconv.Atoi(stamp[i+1:])
+			if err != nil {
+				continue
+			}
+			stamp, seq = stamp[:i], n
+		}
+		t, err := time.ParseInLocation(_databaseBackupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, wrap(err)
+		}
+		backups = append(backups, &DatabaseBackup{
+			Path:   filepath.Join(dir, name),
+			Time:   t,
+			Reason: parts[1],
+			Size:   info.Size(),
+			seq:    seq,
+		})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.After(backups[j].Time)
+		}
+		if backups[i].seq != backups[j].seq {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].Path > backups[j].Path
+	})
+	return backups, nil
+}
+
+func rotateDatabaseBackups(dbPath string, reason string) error {
+	keep, ok := _databaseBackupRetention[reason]
+	if !ok {
+		return nil
+	}
+	backups, err := listDatabaseBackups(dbPath)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, b := range backups {
+		if b.Reason != reason {
+			continue
+		}
+		count++
+		if count <= keep {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil {
+			return errors.Wrapf(err, "remove old database backup %#v", b.Path)
+		}
+		log.Infof("removed old database backup %s", b.Path)
+	}
+	return nil
+}
+
+// ValidateDatabaseBackup checks that the file at path is an intact EggLedger
+// database that this version can open, with readable missions.
+func ValidateDatabaseBackup(path string) error {
+	action := fmt.Sprintf("validate database backup %#v", path)
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return wrap(err)
+	}
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return wrap(err)
+	}
+	defer conn.Close()
+
+	if err := checkSQLiteIntegrity(conn); err != nil {
+		return wrap(err)
+	}
+	var version uint
+	var dirty bool
+	if err := conn.QueryRow(`SELECT version, dirty FROM schema_migrations;`).Scan(&version, &dirty); err != nil {
+		return wrap(errors.Wrap(err, "not an EggLedger database"))
+	}
+	if dirty {
+		return wrap(errors.Errorf("schema version %d is only partially applied", version))
+	}
+	if version > _schemaVersion {
+		return wrap(errors.Errorf("schema version %d is newer than this version of EggLedger supports (%d)",
+			version, _schemaVersion))
+	}
+	var missions int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM mission;`).Scan(&missions); err != nil {
+		return wrap(err)
+	}
+	log.Infof("database backup %s is intact, with %d missions at schema version %d", path, missions, version)
+	return nil
+}
+
+// CheckDatabaseFile runs SQLite's own integrity check on the database file at
+// path, without opening it as a database of the app, so that it also works on
+// databases the app refuses to open.
+func CheckDatabaseFile(path string) error {
+	action := fmt.Sprintf("check database file %#v", path)
+	if _, err := os.Stat(path); err != nil {
+		return errors.Wrap(err, action)
+	}
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return errors.Wrap(err, action)
+	}
+	defer conn.Close()
+	return errors.Wrap(checkSQLiteIntegrity(conn), action)
+}
+
+func checkSQLiteIntegrity(conn *sql.DB) error {
+	var result string
+	if err := conn.QueryRow(`PRAGMA integrity_check;`).Scan(&result); err != nil {
+		return err
+	}
+	if result != "ok" {
+		return errors.Errorf("integrity check failed: %s", result)
+	}
+	return nil
+}
+
+// RestoreDatabaseBackup replaces the database at dbPath with the backup at
+// path, after validating the backup and taking a backup of the current
+// database. The current database needn't be open, nor openable, e.g. after a
+// failed migration. The database is then opened, and migrated if the backup
+// predates the current schema.
+func RestoreDatabaseBackup(dbPath string, path string) error {
+	action := fmt.Sprintf("restore database backup %#v", path)
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	if err := ValidateDatabaseBackup(path); err != nil {
+		return err
+	}
+
+	// Copy the backup next to the database first: the rename below is then
+	// atomic, and the backup itself may be rotated away by the backup of the
+	// current database.
+	tmpPath := dbPath + ".restore"
+	if err := copyFile(path, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return wrap(err)
+	}
+	// The current database is backed up over a connection of its own, which
+	// works whatever its schema.
+	CloseDB()
+	_dbPath = dbPath
+	conn, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL")
+	if err != nil {
+		os.Remove(tmpPath)
+		return wrap(err)
+	}
+	_, err = backupDatabase(conn, dbPath, DatabaseBackupReason_RESTORE)
+	if closeErr := conn.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return wrap(err)
+	}
+
+	// The current database is moved aside rather than overwritten, so that it
+	// can be put back if the backup can't be opened after all.
+	replacedPath := _dbPath + ".replaced"
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(_dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			os.Remove(tmpPath)
+			return wrap(reopenAfterFailedRestore(err))
+		}
+	}
+	if err := os.Rename(_dbPath, replacedPath); err != nil {
+		os.Remove(tmpPath)
+		return wrap(reopenAfterFailedRestore(err))
+	}
+	if err := os.Rename(tmpPath, _dbPath); err != nil {
+		os.Remove(tmpPath)
+		if putBackErr := os.Rename(replacedPath, _dbPath); putBackErr != nil {
+			return wrap(errors.Wrapf(err, "the current database is left at %#v (putting it back failed: %s)",
+				replacedPath, putBackErr))
+		}
+		return wrap(reopenAfterFailedRestore(err))
+	}
+	if err := openDB(_dbPath); err != nil {
+		CloseDB()
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(_dbPath + suffix)
+		}
+		if putBackErr := os.Rename(replacedPath, _dbPath); putBackErr != nil {
+			return wrap(errors.Wrapf(err, "the previous database is left at %#v (putting it back failed: %s)",
+				replacedPath, putBackErr))
+		}
+		return wrap(reopenAfterFailedRestore(err))
+	}
+	if err := os.Remove(replacedPath); err != nil {
+		log.Errorf("error removing replaced database %#v, which is also backed up: %s", replacedPath, err)
+	}
+	log.Infof("restored database from %s", path)
+	return nil
+}
+
+// reopenAfterFailedRestore reopens the database left in place by a failed
+// restore, and returns the error that made the restore fail.
+func reopenAfterFailedRestore(err error) error {
+	if reopenErr := openDB(_dbPath); reopenErr != nil {
+		return errors.Wrapf(err, "the current database couldn't be reopened either (%s)", reopenErr)
+	}
+	return errors.Wrap(err, "the current database is kept")
+}
+
+// CloseDB closes the database handle, if any, ignoring errors. The next InitDB
+// opens the database afresh.
+func CloseDB() {
+	if _db != nil {
+		_ = _db.Close()
+		_db = nil
+	}
+}
+
+// backupBeforeMigration backs up a database about to be migrated to the target
+// schema version. Fresh databases, with nothing to lose, and databases already
+// at the target are skipped.
+func backupBeforeMigration(conn *sql.DB, dbPath string, m *migrate.Migrate, target uint) error {
+	version, _, err := m.Version()
+	if err == migrate.ErrNilVersion {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read schema version")
+	}
+	if version == target {
+		return nil
+	}
+	if _, err := backupDatabase(conn, dbPath, DatabaseBackupReason_MIGRATION); err != nil {
+		return errors.Wrap(err, "refusing to migrate without a backup")
+	}
+	return nil
+}
+
+func databaseBackupDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), _databaseBackupDir)
+}
+
+// databaseBackupPrefix is the database file name without extension, e.g. data
+// for data.db.
+func databaseBackupPrefix(dbPath string) string {
+	base := filepath.Base(dbPath)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func copyFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := out.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	if _, err = io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
+}
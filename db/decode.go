@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+	"runtime"
+	"sync"
+
+	"github.com/fanaticscripter/EggLedger/api"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// decodeStoredMission decompresses and decodes a stored mission payload.
+func decodeStoredMission(startTimestamp float64, compressedPayload []byte) (*ei.CompleteMissionResponse, error) {
+	completePayload, err := decompress(compressedPayload)
+	if err != nil {
+		return nil, err
+	}
+	m, err := api.DecodeCompleteMissionPayload(completePayload)
+	if err != nil {
+		return nil, err
+	}
+	// /ei_afx/complete_mission response leaves out start_time_derived, so we
+	// have to manually attach it.
+	m.Info.StartTimeDerived = &startTimestamp
+	return m, nil
+}
+
+// decodeMissionRows decodes rows of (start_timestamp, complete_payload) on a
+// pool of workers, one per CPU, and calls f with the decoded missions in row
+// order on the calling goroutine. At most a few rows per worker are read ahead
+// of f, so memory use stays bounded. It stops at the first error, and returns
+// f's errors as is.
+func decodeMissionRows(rows *sql.Rows, f func(*ei.CompleteMissionResponse) error) error {
+	workers := runtime.GOMAXPROCS(0)
+	if workers == 1 {
+		// Nothing to gain from handing rows over to another goroutine.
+		for rows.Next() {
+			var startTimestamp float64
+			var compressedPayload []byte
+			if err := rows.Scan(&startTimestamp, &compressedPayload); err != nil {
+				return err
+			}
+			m, err := decodeStoredMission(startTimestamp, compressedPayload)
+			if err != nil {
+				return err
+			}
+			if err := f(m); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}
+
+	type decoded struct {
+		m   *ei.CompleteMissionResponse
+		err error
+	}
+	type job struct {
+		startTimestamp    float64
+		compressedPayload []byte
+		result            chan decoded
+	}
+	jobs := make(chan job)
+	// Result channels are queued in row order as rows are read, so that
+	// missions are handed to f in order however fast each is decoded.
+	pending := make(chan chan decoded, 2*workers)
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				m, err := decodeStoredMission(j.startTimestamp, j.compressedPayload)
+				j.result <- decoded{m, err}
+			}
+		}()
+	}
+
+	var readErr error
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for rows.Next() {
+			j := job{result: make(chan decoded, 1)}
+			if err := rows.Scan(&j.startTimestamp, &j.compressedPayload); err != nil {
+				readErr = err
+				return
+			}
+			select {
+			case pending <- j.result:
+			case <-stop:
+				return
+			}
+			select {
+			case jobs <- j:
+			case <-stop:
+				return
+			}
+		}
+		readErr = rows.Err()
+	}()
+
+	var err error
+	for result := range pending {
+		d := <-result
+		if d.err != nil {
+			err = d.err
+			break
+		}
+		if err = f(d.m); err != nil {
+			break
+		}
+	}
+	close(stop)
+	// The reader and workers must be done with rows before the caller closes
+	// them.
+	for range pending {
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return readErr
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+func TestIteratePlayerCompleteMissionsOrder(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	openTestDB(t)
+	insertTestMissions(t, "EI1", 1000)
+	insertTestMissions(t, "EI2", 10)
+
+	var expected []string
+	rows, err := _db.Query(`SELECT mission_id FROM mission WHERE player_id = 'EI1' ORDER BY start_timestamp;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var missionId string
+		if err := rows.Scan(&missionId); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, missionId)
+	}
+	rows.Close()
+
+	for run := 0; run < 3; run++ {
+		i := 0
+		var previous float64
+		err := IteratePlayerCompleteMissions("EI1", func(m *ei.CompleteMissionResponse) error {
+			if i >= len(expected) {
+				t.Fatalf("more than %d missions", len(expected))
+			}
+			if id := m.GetInfo().GetIdentifier(); id != expected[i] {
+				t.Fatalf("mission %d is %s, expected %s", i, id, expected[i])
+			}
+			if start := m.GetInfo().GetStartTimeDerived(); start < previous {
+				t.Fatalf("mission %d started at %f, before the previous one at %f", i, start, previous)
+			} else {
+				previous = start
+			}
+			i++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i != len(expected) {
+			t.Fatalf("%d missions, expected %d", i, len(expected))
+		}
+	}
+}
+
+func TestIteratePlayerCompleteMissionsStops(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	openTestDB(t)
+	insertTestMissions(t, "EI1", 600)
+	if _, err := _db.Exec(`UPDATE mission SET complete_payload = 'corrupt'
+		WHERE rowid = (SELECT rowid FROM mission ORDER BY start_timestamp LIMIT 1 OFFSET 300);`); err != nil {
+		t.Fatal(err)
+	}
+	errStop := errors.New("stop")
+
+	for _, test := range []struct {
+		name     string
+		stopAt   int
+		expected int
+	}{
+		{"callback error", 100, 100},
+		{"decode error", 0, 300},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			goroutines := runtime.NumGoroutine()
+			n := 0
+			err := IteratePlayerCompleteMissions("EI1", func(m *ei.CompleteMissionResponse) error {
+				n++
+				if n == test.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			if test.stopAt > 0 && err != errStop {
+				t.Errorf("error %v, expected the callback's", err)
+			}
+			if test.stopAt == 0 && (err == nil || err == errStop) {
+				t.Errorf("error %v, expected a decode error", err)
+			}
+			if n != test.expected {
+				t.Errorf("callback called %d times, expected %d", n, test.expected)
+			}
+			// Workers exit asynchronously once they are done.
+			deadline := time.Now().Add(5 * time.Second)
+			for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if leaked := runtime.NumGoroutine() - goroutines; leaked > 0 {
+				t.Errorf("%d goroutines leaked", leaked)
+			}
+			var count int
+			if err := _db.QueryRow(`SELECT COUNT(*) FROM mission;`).Scan(&count); err != nil {
+				t.Errorf("database unusable after stopping: %s", err)
+			}
+		})
+	}
+}
+
+func BenchmarkIteratePlayerCompleteMissions(b *testing.B) {
+	const missions = 10000
+	openTestDB(b)
+	insertTestMissions(b, "EI1", missions)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		err := IteratePlayerCompleteMissions("EI1", func(m *ei.CompleteMissionResponse) error {
+			n++
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n != missions {
+			b.Fatalf("%d missions, expected %d", n, missions)
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*missions), "ns/mission")
+}
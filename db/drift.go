@@ -0,0 +1,149 @@
+package db
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fanaticscripter/EggLedger/api"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// SchemaDriftReport lists fields present in stored payloads but missing from
+// ei.proto, i.e. added to the game since ei.proto was last updated. Such fields
+// are kept in the stored payloads but can't be exported.
+type SchemaDriftReport struct {
+	Backups  int
+	Missions int
+	// Undecodable is the number of payloads that failed to decompress or
+	// decode, which are otherwise skipped.
+	Undecodable int
+	Fields      []*DriftField
+}
+
+type DriftField struct {
+	ei.UnknownField
+	// Backups and Missions are the numbers of payloads containing the field.
+	Backups  int
+	Missions int
+	// FirstSeen and LastSeen are the earliest and latest backup or mission
+	// start timestamps of payloads containing the field.
+	FirstSeen float64
+	LastSeen  float64
+}
+
+// ScanSchemaDrift decodes every stored backup and mission payload and reports
+// the unknown fields found, ordered by message and field number.
+func ScanSchemaDrift() (*SchemaDriftReport, error) {
+	action := "scan stored payloads for unknown fields"
+	report := &SchemaDriftReport{}
+	fields := make(map[ei.UnknownField]*DriftField)
+	record := func(msg proto.Message, timestamp float64, isBackup bool) {
+		for _, f := range ei.FindUnknownFields(msg) {
+			df, ok := fields[f]
+			if !ok {
+				df = &DriftField{UnknownField: f, FirstSeen: timestamp, LastSeen: timestamp}
+				fields[f] = df
+			}
+			if isBackup {
+				df.Backups++
+			} else {
+				df.Missions++
+			}
+			if timestamp < df.FirstSeen {
+				df.FirstSeen = timestamp
+			}
+			if timestamp > df.LastSeen {
+				df.LastSeen = timestamp
+			}
+		}
+	}
+
+	err := transact(action, func(tx *sql.Tx) error {
+		// Payloads are decoded while iterating rather than collected first,
+		// since backups in particular can be large.
+		rows, err := tx.Query(`SELECT id, player_id, backed_up_at, payload, payload_authenticated FROM backup;`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			var playerId string
+			var timestamp float64
+			var compressedPayload []byte
+			var authenticated bool
+			if err := rows.Scan(&id, &playerId, &timestamp, &compressedPayload, &authenticated); err != nil {
+				return err
+			}
+			report.Backups++
+			payload, err := decompress(compressedPayload)
+			if err != nil {
+				log.Warnf("%s: backup %d: %s", playerId, id, err)
+				report.Undecodable++
+				continue
+			}
+			msg := &ei.EggIncFirstContactResponse{}
+			// Authenticated payloads are from the since retired /ei/first_contact.
+			if err := api.DecodeAPIResponse("/ei/first_contact", payload, msg, authenticated); err != nil {
+				log.Warnf("%s: backup %d: %s", playerId, id, errors.Cause(err))
+				report.Undecodable++
+				continue
+			}
+			record(msg, timestamp, true)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(`SELECT player_id, mission_id, start_timestamp, complete_payload FROM mission;`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var playerId, missionId string
+			var startTimestamp float64
+			var compressedPayload []byte
+			if err := rows.Scan(&playerId, &missionId, &startTimestamp, &compressedPayload); err != nil {
+				return err
+			}
+			report.Missions++
+			payload, err := decompress(compressedPayload)
+			if err != nil {
+				log.Warnf("%s: mission %s: %s", playerId, missionId, err)
+				report.Undecodable++
+				continue
+			}
+			m, err := api.DecodeCompleteMissionPayload(payload)
+			if err != nil {
+				log.Warnf("%s: mission %s: %s", playerId, missionId, errors.Cause(err))
+				report.Undecodable++
+				continue
+			}
+			record(m, startTimestamp, false)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		report.Fields = append(report.Fields, f)
+	}
+	sort.Slice(report.Fields, func(i, j int) bool {
+		fi, fj := report.Fields[i], report.Fields[j]
+		if fi.Message != fj.Message {
+			return fi.Message < fj.Message
+		}
+		if fi.Number != fj.Number {
+			return fi.Number < fj.Number
+		}
+		return fi.WireType < fj.WireType
+	})
+	return report, nil
+}
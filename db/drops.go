@@ -0,0 +1,186 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// DropQuery selects items dropped by a player's missions. Nil or zero fields
+// are not filtered on.
+type DropQuery struct {
+	PlayerId string
+	Family   *ei.ArtifactSpec_Name
+	Tier     int
+	Rarity   *ei.ArtifactSpec_Rarity
+	// Since and Until bound the mission launch time (inclusive and exclusive
+	// respectively) as Unix timestamps.
+	Since float64
+	Until float64
+}
+
+type Drop struct {
+	MissionId       string
+	StartTimestamp  float64
+	DurationSeconds float64
+	Ship            ei.MissionInfo_Spaceship
+	DurationType    ei.MissionInfo_DurationType
+	Level           uint32
+	// DropIndex is the 0-based index of the item among the mission's drops.
+	DropIndex int
+	Spec      *ei.ArtifactSpec
+}
+
+// QueryDrops returns drops matching the query, in chronological order.
+func QueryDrops(q DropQuery) ([]*Drop, error) {
+	action := fmt.Sprintf("query drops for player %s from database", q.PlayerId)
+	conditions := []string{"player_id = ?"}
+	args := []interface{}{q.PlayerId}
+	if q.Family != nil {
+		conditions = append(conditions, "artifact_family = ?")
+		args = append(args, int32(*q.Family))
+	}
+	if q.Tier != 0 {
+		conditions = append(conditions, "artifact_tier = ?")
+		args = append(args, q.Tier)
+	}
+	if q.Rarity != nil {
+		conditions = append(conditions, "artifact_rarity = ?")
+		args = append(args, int32(*q.Rarity))
+	}
+	if q.Since != 0 {
+		conditions = append(conditions, "start_timestamp >= ?")
+		args = append(args, q.Since)
+	}
+	if q.Until != 0 {
+		conditions = append(conditions, "start_timestamp < ?")
+		args = append(args, q.Until)
+	}
+	var drops []*Drop
+	err := transact(action, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT mission_id, start_timestamp, duration_seconds, ship, duration_type, level,
+				drop_index, artifact_name, artifact_level, artifact_rarity
+			FROM artifact_drop
+			WHERE `+strings.Join(conditions, " AND ")+`
+			ORDER BY start_timestamp, drop_index;`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var d Drop
+			var name ei.ArtifactSpec_Name
+			var level ei.ArtifactSpec_Level
+			var rarity ei.ArtifactSpec_Rarity
+			if err := rows.Scan(&d.MissionId, &d.StartTimestamp, &d.DurationSeconds, &d.Ship, &d.DurationType, &d.Level,
+				&d.DropIndex, &name, &level, &rarity); err != nil {
+				return err
+			}
+			d.Spec = &ei.ArtifactSpec{Name: &name, Level: &level, Rarity: &rarity}
+			drops = append(drops, &d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return drops, nil
+}
+
+// insertDrops indexes the drops of a stored mission, and marks it indexed.
+func insertDrops(tx *sql.Tx, playerId string, missionId string, startTimestamp float64, m *ei.CompleteMissionResponse) error {
+	info := m.GetInfo()
+	for i, a := range m.GetArtifacts() {
+		spec := a.GetSpec()
+		_, err := tx.Exec(`INSERT INTO
+			artifact_drop(player_id, mission_id, drop_index, start_timestamp, duration_seconds, ship, duration_type, level,
+				artifact_name, artifact_level, artifact_rarity, artifact_family, artifact_tier)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+			playerId, missionId, i, startTimestamp, info.GetDurationSeconds(),
+			int32(info.GetShip()), int32(info.GetDurationType()), info.GetLevel(),
+			int32(spec.GetName()), int32(spec.GetLevel()), int32(spec.GetRarity()),
+			int32(spec.Family()), spec.TierNumber())
+		if err != nil {
+			return err
+		}
+	}
+	return markDropsIndexed(tx, playerId, missionId)
+}
+
+func markDropsIndexed(tx *sql.Tx, playerId string, missionId string) error {
+	_, err := tx.Exec(`UPDATE mission SET drops_indexed = TRUE
+		WHERE player_id = ? AND mission_id = ?;`,
+		playerId, missionId)
+	return err
+}
+
+// _dropIndexBatchSize is the number of missions indexed per transaction by
+// indexMissingDrops, which bounds its memory use.
+const _dropIndexBatchSize = 500
+
+// indexMissingDrops populates the drop index for stored missions that are not
+// yet indexed, e.g. missions stored before the index was introduced. Missions
+// whose payloads can't be decoded are logged and marked indexed all the same,
+// so that they aren't tried again every time; check-db deals with them.
+func indexMissingDrops() error {
+	action := "index drops of stored missions"
+	type row struct {
+		playerId          string
+		missionId         string
+		startTimestamp    float64
+		compressedPayload []byte
+	}
+	total := 0
+	for {
+		var batch []row
+		err := transact(action, func(tx *sql.Tx) error {
+			rows, err := tx.Query(`SELECT player_id, mission_id, start_timestamp, complete_payload FROM mission
+				WHERE NOT drops_indexed
+				LIMIT ?;`, _dropIndexBatchSize)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var r row
+				if err := rows.Scan(&r.playerId, &r.missionId, &r.startTimestamp, &r.compressedPayload); err != nil {
+					return err
+				}
+				batch = append(batch, r)
+			}
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			for _, r := range batch {
+				m, err := decodeStoredMission(r.startTimestamp, r.compressedPayload)
+				if err != nil {
+					log.Errorf("%s: mission %s for player %s: %s", action, r.missionId, r.playerId, err)
+					if err := markDropsIndexed(tx, r.playerId, r.missionId); err != nil {
+						return errors.Wrapf(err, "mission %s for player %s", r.missionId, r.playerId)
+					}
+					continue
+				}
+				if err := insertDrops(tx, r.playerId, r.missionId, r.startTimestamp, m); err != nil {
+					return errors.Wrapf(err, "mission %s for player %s", r.missionId, r.playerId)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		total += len(batch)
+		if len(batch) < _dropIndexBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		log.Infof("indexed drops of %d stored missions", total)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type PlayerDeletion struct {
+	Missions    int
+	Backups     int
+	Quarantined int
+}
+
+// DeletePlayerData deletes everything stored for a player, including
+// quarantined rows, retrains the compression dictionary, which may hold some of
+// the player's payloads, on the remaining missions, then vacuums the database
+// so that the deleted data is gone from the file too. Automatic database
+// backups still have it until they are rotated out. Compaction is held off
+// meanwhile, so that it doesn't write while the database is vacuumed.
+func DeletePlayerData(playerId string) (*PlayerDeletion, error) {
+	_compactionLock.Lock()
+	defer _compactionLock.Unlock()
+	action := fmt.Sprintf("delete data of player %s from database", playerId)
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	deletion := &PlayerDeletion{}
+	err := transact(action, func(tx *sql.Tx) error {
+		deleteRows := func(table string) (int, error) {
+			res, err := tx.Exec(`DELETE FROM `+table+` WHERE player_id = ?;`, playerId)
+			if err != nil {
+				return 0, err
+			}
+			n, err := res.RowsAffected()
+			return int(n), err
+		}
+		// Drops and unknown values would go with their missions anyway, but
+		// don't rely on foreign keys being enforced.
+		for _, table := range []string{"artifact_drop", "unknown_value"} {
+			if _, err := deleteRows(table); err != nil {
+				return err
+			}
+		}
+		var err error
+		if deletion.Missions, err = deleteRows("mission"); err != nil {
+			return err
+		}
+		if deletion.Backups, err = deleteRows("backup"); err != nil {
+			return err
+		}
+		for _, table := range []string{"quarantined_mission", "quarantined_backup"} {
+			n, err := deleteRows(table)
+			if err != nil {
+				return err
+			}
+			deletion.Quarantined += n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("%s: deleted %d missions, %d backups and %d quarantined rows",
+		playerId, deletion.Missions, deletion.Backups, deletion.Quarantined)
+
+	if _, err := retrainCompressionDictionary(); err != nil {
+		return deletion, wrap(err)
+	}
+
+	// VACUUM goes through the WAL like any other write, so checkpoint it
+	// afterwards to also get the old pages out of the WAL file.
+	if _, err := _db.Exec(`VACUUM;`); err != nil {
+		return deletion, wrap(err)
+	}
+	if _, err := _db.Exec(`PRAGMA wal_checkpoint(TRUNCATE);`); err != nil {
+		return deletion, wrap(err)
+	}
+	return deletion, nil
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// InsertImportedMissions stores missions reconstructed from other sources than
+// the game server, flagged as imported. Missions already stored, imported or
+// not, are skipped. Either all missions are inserted or none is.
+func InsertImportedMissions(playerId string, missions []*ei.CompleteMissionResponse) (inserted int, skipped int, err error) {
+	action := fmt.Sprintf("insert imported missions for player %s into database", playerId)
+	err = transact(action, func(tx *sql.Tx) error {
+		for _, m := range missions {
+			info := m.GetInfo()
+			missionId := info.GetIdentifier()
+			startTimestamp := info.GetStartTimeDerived()
+			var exists bool
+			row := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM mission WHERE player_id = ? AND mission_id = ?);`,
+				playerId, missionId)
+			if err := row.Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				skipped++
+				continue
+			}
+			// Stored the same way as server payloads, so that they decode the
+			// same way.
+			message, err := proto.Marshal(m)
+			if err != nil {
+				return errors.Wrapf(err, "mission %s", missionId)
+			}
+			payload, err := proto.Marshal(&ei.AuthenticatedMessage{Message: message})
+			if err != nil {
+				return errors.Wrapf(err, "mission %s", missionId)
+			}
+			compressedPayload, codec, err := compressMission(payload)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO
+				mission(player_id, mission_id, start_timestamp, complete_payload, codec, imported)
+				VALUES (?, ?, ?, ?, ?, TRUE);`,
+				playerId, missionId, startTimestamp, compressedPayload, codec)
+			if err != nil {
+				return err
+			}
+			if err := insertDrops(tx, playerId, missionId, startTimestamp, m); err != nil {
+				return err
+			}
+			inserted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return inserted, skipped, nil
+}
+
+// RetrievePlayerImportedMissionIds retrieves IDs of the player's imported
+// missions.
+func RetrievePlayerImportedMissionIds(playerId string) (map[string]bool, error) {
+	action := fmt.Sprintf("retrieve imported mission ids for player %s from database", playerId)
+	missionIds := make(map[string]bool)
+	err := transact(action, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT mission_id FROM mission
+			WHERE player_id = ? AND imported;`, playerId)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var missionId string
+			if err := rows.Scan(&missionId); err != nil {
+				return err
+			}
+			missionIds[missionId] = true
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return missionIds, nil
+}
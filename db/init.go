@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"os"
 	"path/filepath"
-	"sync"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pkg/errors"
@@ -12,34 +11,52 @@ import (
 )
 
 var (
-	_db         *sql.DB
-	_initDBOnce sync.Once
+	_db     *sql.DB
+	_dbPath string
 )
 
+// InitDB opens the database at path, unless it's already open. Failing to open
+// it, e.g. because of a failed migration, leaves it closed, so that it can be
+// restored.
 func InitDB(path string) error {
-	var err error
-	_initDBOnce.Do(func() {
-		log.Debugf("database path: %s", path)
-
-		parentDir := filepath.Dir(path)
-		err = os.MkdirAll(parentDir, 0o755)
-		if err != nil {
-			err = errors.Wrapf(err, "failed to create parent directory %#v for database", parentDir)
-			return
-		}
-
-		err = runMigrations(path)
-		if err != nil {
-			err = errors.Wrapf(err, "error occurred during schema migrations")
-			return
-		}
-
-		_db, err = sql.Open("sqlite3", path+"?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=10")
-		if err != nil {
-			err = errors.Wrapf(err, "failed to open SQLite3 database %#v", path)
-			return
-		}
-		err = nil
-	})
-	return err
+	if _db != nil && _dbPath == path {
+		return nil
+	}
+	log.Debugf("database path: %s", path)
+
+	parentDir := filepath.Dir(path)
+	if err := os.MkdirAll(parentDir, 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create parent directory %#v for database", parentDir)
+	}
+	return openDB(path)
+}
+
+// openDB migrates and opens the database at path, also when reopening it after
+// a restore.
+func openDB(path string) error {
+	err := runMigrations(path)
+	if err != nil {
+		return errors.Wrapf(err, "error occurred during schema migrations")
+	}
+
+	// Background compaction, syncs and VACUUM after forgetting an account may
+	// write at the same time, so writers wait their turn for a while rather
+	// than fail with "database is locked".
+	_db, err = sql.Open("sqlite3", path+"?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=10000")
+	if err != nil {
+		return errors.Wrapf(err, "failed to open SQLite3 database %#v", path)
+	}
+	_dbPath = path
+
+	if err := loadCompressionDictionaries(); err != nil {
+		CloseDB()
+		return err
+	}
+
+	// The drop index can always be rebuilt later, so failing to build it
+	// isn't fatal.
+	if indexErr := indexMissingDrops(); indexErr != nil {
+		log.Error(indexErr)
+	}
+	return nil
 }
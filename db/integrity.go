@@ -0,0 +1,223 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/fanaticscripter/EggLedger/api"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+var (
+	// Missions were introduced with artifacts in 2021, and backups can't
+	// predate the game; anything earlier is corrupt.
+	_minMissionTimestamp = float64(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).Unix())
+	_minBackupTimestamp  = float64(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC).Unix())
+	// Allows for clock skew between the server and this machine.
+	_maxTimestampSkew = 24 * time.Hour
+)
+
+type IntegrityIssue struct {
+	// Table is mission, backup, or database for problems reported by SQLite.
+	Table    string
+	PlayerId string
+	// Key is the mission id or backup id.
+	Key     string
+	Problem string
+}
+
+type IntegrityReport struct {
+	Missions int
+	Backups  int
+	Issues   []*IntegrityIssue
+	// Quarantined is the number of rows moved to quarantine.
+	Quarantined int
+}
+
+// CheckIntegrity checks that every stored mission and backup decompresses and
+// decodes, and has a sane timestamp, on top of SQLite's own integrity check. If
+// quarantine is true, rows with problems are moved to quarantine tables, so
+// that missions are fetched again on the next sync.
+func CheckIntegrity(quarantine bool) (*IntegrityReport, error) {
+	action := "check database integrity"
+	report := &IntegrityReport{}
+	now := time.Now()
+	maxTimestamp := float64(now.Add(_maxTimestampSkew).Unix())
+	checkTimestamp := func(value interface{}, min float64) string {
+		var t float64
+		switch v := value.(type) {
+		case float64:
+			t = v
+		case int64:
+			t = float64(v)
+		default:
+			return fmt.Sprintf("timestamp %#v is not a number", v)
+		}
+		if math.IsNaN(t) || t < min || t > maxTimestamp {
+			return fmt.Sprintf("timestamp %v out of range", t)
+		}
+		return ""
+	}
+
+	err := transact(action, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`PRAGMA integrity_check;`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var result string
+			if err := rows.Scan(&result); err != nil {
+				return err
+			}
+			if result != "ok" {
+				report.Issues = append(report.Issues, &IntegrityIssue{Table: "database", Problem: result})
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var missionIssues []*IntegrityIssue
+		rows, err = tx.Query(`SELECT player_id, mission_id, start_timestamp, complete_payload FROM mission;`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var playerId, missionId string
+			var startTimestamp interface{}
+			var compressedPayload []byte
+			if err := rows.Scan(&playerId, &missionId, &startTimestamp, &compressedPayload); err != nil {
+				return err
+			}
+			report.Missions++
+			problem := checkTimestamp(startTimestamp, _minMissionTimestamp)
+			if problem == "" {
+				problem = checkMissionPayload(missionId, compressedPayload)
+			}
+			if problem != "" {
+				missionIssues = append(missionIssues, &IntegrityIssue{
+					Table:    "mission",
+					PlayerId: playerId,
+					Key:      missionId,
+					Problem:  problem,
+				})
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var backupIssues []*IntegrityIssue
+		rows, err = tx.Query(`SELECT id, player_id, backed_up_at, payload, payload_authenticated FROM backup;`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			var playerId string
+			var backedUpAt interface{}
+			var compressedPayload []byte
+			var authenticated bool
+			if err := rows.Scan(&id, &playerId, &backedUpAt, &compressedPayload, &authenticated); err != nil {
+				return err
+			}
+			report.Backups++
+			problem := checkTimestamp(backedUpAt, _minBackupTimestamp)
+			if problem == "" {
+				problem = checkBackupPayload(compressedPayload, authenticated)
+			}
+			if problem != "" {
+				backupIssues = append(backupIssues, &IntegrityIssue{
+					Table:    "backup",
+					PlayerId: playerId,
+					Key:      fmt.Sprint(id),
+					Problem:  problem,
+				})
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		report.Issues = append(report.Issues, missionIssues...)
+		report.Issues = append(report.Issues, backupIssues...)
+
+		if !quarantine {
+			return nil
+		}
+		quarantinedAt := float64(now.Unix())
+		for _, issue := range missionIssues {
+			_, err := tx.Exec(`INSERT INTO
+				quarantined_mission(player_id, mission_id, start_timestamp, complete_payload, codec, imported, problem, quarantined_at)
+				SELECT player_id, mission_id, start_timestamp, complete_payload, codec, imported, ?, ? FROM mission
+				WHERE player_id = ? AND mission_id = ?;`,
+				issue.Problem, quarantinedAt, issue.PlayerId, issue.Key)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`DELETE FROM mission WHERE player_id = ? AND mission_id = ?;`,
+				issue.PlayerId, issue.Key)
+			if err != nil {
+				return err
+			}
+			report.Quarantined++
+		}
+		for _, issue := range backupIssues {
+			_, err := tx.Exec(`INSERT INTO
+				quarantined_backup(player_id, backed_up_at, payload, payload_authenticated, problem, quarantined_at)
+				SELECT player_id, backed_up_at, payload, payload_authenticated, ?, ? FROM backup
+				WHERE id = ?;`,
+				issue.Problem, quarantinedAt, issue.Key)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`DELETE FROM backup WHERE id = ?;`, issue.Key)
+			if err != nil {
+				return err
+			}
+			report.Quarantined++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// checkMissionPayload returns the problem with a stored mission payload, or ""
+// if there's none.
+func checkMissionPayload(missionId string, compressedPayload []byte) string {
+	payload, err := decompress(compressedPayload)
+	if err != nil {
+		return fmt.Sprintf("payload doesn't decompress: %s", err)
+	}
+	m, err := api.DecodeCompleteMissionPayload(payload)
+	if err != nil {
+		// The error is stripped of context, which includes the whole payload.
+		return fmt.Sprintf("payload doesn't decode: %s", errors.Cause(err))
+	}
+	if id := m.GetInfo().GetIdentifier(); id != missionId {
+		return fmt.Sprintf("payload is of mission %#v", id)
+	}
+	return ""
+}
+
+// checkBackupPayload returns the problem with a stored backup payload, or "" if
+// there's none.
+func checkBackupPayload(compressedPayload []byte, authenticated bool) string {
+	payload, err := decompress(compressedPayload)
+	if err != nil {
+		return fmt.Sprintf("payload doesn't decompress: %s", err)
+	}
+	if err := api.DecodeAPIResponse("/ei/first_contact", payload, &ei.EggIncFirstContactResponse{}, authenticated); err != nil {
+		return fmt.Sprintf("payload doesn't decode: %s", errors.Cause(err))
+	}
+	return ""
+}
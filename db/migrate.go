@@ -11,12 +11,20 @@ import (
 	"github.com/pkg/errors"
 )
 
-const _schemaVersion = 3
+const _schemaVersion = 10
 
 //go:embed migrations/*.sql
 var _fs embed.FS
 
-func runMigrations(dbPath string) (err error) {
+func runMigrations(dbPath string) error {
+	return migrateDB(dbPath, _schemaVersion)
+}
+
+// migrateDB migrates the database at dbPath up or down to the target schema
+// version, backing it up first unless it's fresh or already at the target.
+// Databases with a schema newer than _schemaVersion, i.e. written by a newer
+// version of EggLedger, are never touched.
+func migrateDB(dbPath string, target uint) (err error) {
 	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on&_journal_mode=WAL")
 	if err != nil {
 		return errors.Wrapf(err, "failed to open SQLite3 database %#v for migrations", dbPath)
@@ -43,7 +51,42 @@ func runMigrations(dbPath string) (err error) {
 		err = errors.Wrap(err, "failed to initialize schema migrator")
 		return
 	}
-	err = m.Migrate(_schemaVersion)
+
+	version, dirty, err := m.Version()
+	if err == migrate.ErrNilVersion {
+		// Fresh database, nothing to check or back up.
+		version, err = 0, nil
+	} else if err != nil {
+		err = errors.Wrapf(err, "failed to read schema version of database %#v", dbPath)
+		return
+	} else {
+		if dirty {
+			err = errors.Errorf("a previous migration of database %#v to schema version %d failed halfway; "+
+				"restore a backup with EggLedger restore-db", dbPath, version)
+			return
+		}
+		if version > _schemaVersion {
+			err = errors.Errorf("database %#v has schema version %d, which is newer than this version of EggLedger "+
+				"supports (%d); upgrade EggLedger, downgrade the database with "+
+				"EggLedger migrate-db -to %d using the newer version, or restore an older backup with EggLedger restore-db",
+				dbPath, version, _schemaVersion, _schemaVersion)
+			return
+		}
+	}
+	err = backupBeforeMigration(db, dbPath, m, target)
+	if err != nil {
+		return
+	}
+
+	if target == 0 {
+		if version > 0 {
+			err = m.Down()
+		} else {
+			err = migrate.ErrNoChange
+		}
+	} else {
+		err = m.Migrate(target)
+	}
 	if err != nil && err != migrate.ErrNoChange {
 		err = errors.Wrapf(err, "failed to migrate schemas in database %#v", dbPath)
 		return
@@ -51,3 +94,31 @@ func runMigrations(dbPath string) (err error) {
 	err = nil
 	return
 }
+
+// MigrateDatabase migrates the database to the target schema version, which
+// may be older than the current one, e.g. to hand the database over to an
+// older version of EggLedger. The database is closed afterwards, since this
+// version can't work with older schemas.
+func MigrateDatabase(target uint) error {
+	if target > _schemaVersion {
+		return errors.Errorf("schema version %d is newer than this version of EggLedger supports (%d)",
+			target, _schemaVersion)
+	}
+	// Versions before the codec column only know gzip.
+	if target < 9 {
+		if err := decompactMissionPayloads(); err != nil {
+			return err
+		}
+	}
+	if err := _db.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close database %#v", _dbPath)
+	}
+	_db = nil
+	return migrateDB(_dbPath, target)
+}
+
+// SchemaVersion is the schema version of the database this version of
+// EggLedger works with.
+func SchemaVersion() uint {
+	return _schemaVersion
+}
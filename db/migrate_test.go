@@ -0,0 +1,248 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+var _fixturePayload = []byte("mission payload")
+
+// migrationFixtures insert a player's mission and backup into a database at
+// the given schema version, in the shape that version stores them.
+var migrationFixtures = []struct {
+	version uint
+	insert  string
+}{
+	{1, `INSERT INTO mission VALUES ('EI1', 'm1', 1000, ?);
+		INSERT INTO backup VALUES ('EI1', 2000, ?);`},
+	{2, `INSERT INTO mission VALUES ('EI1', 'm1', 1000, ?);
+		INSERT INTO backup (player_id, backed_up_at, payload) VALUES ('EI1', 2000, ?);`},
+	{3, `INSERT INTO mission VALUES ('EI1', 'm1', 1000, ?);
+		INSERT INTO backup (player_id, backed_up_at, payload, payload_authenticated) VALUES ('EI1', 2000, ?, TRUE);`},
+	{4, `INSERT INTO mission VALUES ('EI1', 'm1', 1000, ?);
+		INSERT INTO backup (player_id, backed_up_at, payload, payload_authenticated) VALUES ('EI1', 2000, ?, TRUE);`},
+	{5, `INSERT INTO mission VALUES ('EI1', 'm1', 1000, ?);
+		INSERT INTO backup (player_id, backed_up_at, payload, payload_authenticated) VALUES ('EI1', 2000, ?, TRUE);
+		INSERT INTO artifact_drop VALUES ('EI1', 'm1', 0, 1000, 3600, 0, 0, 0, 1, 0, 0, 1, 1);`},
+	{6, `INSERT INTO mission VALUES ('EI1', 'm1', 1000, ?);
+		INSERT INTO backup (player_id, backed_up_at, payload, payload_authenticated) VALUES ('EI1', 2000, ?, TRUE);
+		INSERT INTO artifact_drop VALUES ('EI1', 'm1', 0, 1000, 3600, 0, 0, 0, 1, 0, 0, 1, 1);`},
+	{7, `INSERT INTO mission VALUES ('EI1', 'm1', 1000, ?, FALSE);
+		INSERT INTO backup (player_id, backed_up_at, payload, payload_authenticated) VALUES ('EI1', 2000, ?, TRUE);
+		INSERT INTO artifact_drop VALUES ('EI1', 'm1', 0, 1000, 3600, 0, 0, 0, 1, 0, 0, 1, 1);`},
+	{8, `INSERT INTO mission VALUES ('EI1', 'm1', 1000, ?, FALSE);
+		INSERT INTO backup (player_id, backed_up_at, payload, payload_authenticated) VALUES ('EI1', 2000, ?, TRUE);
+		INSERT INTO artifact_drop VALUES ('EI1', 'm1', 0, 1000, 3600, 0, 0, 0, 1, 0, 0, 1, 1);`},
+}
+
+func TestMigrations(t *testing.T) {
+	for _, fixture := range migrationFixtures {
+		fixture := fixture
+		t.Run(fmt.Sprintf("v%d", fixture.version), func(t *testing.T) {
+			dbPath := filepath.Join(t.TempDir(), "data.db")
+			if err := migrateDB(dbPath, fixture.version); err != nil {
+				t.Fatal(err)
+			}
+			withTestConn(t, dbPath, func(conn *sql.DB) {
+				if _, err := conn.Exec(fixture.insert, _fixturePayload, _fixturePayload); err != nil {
+					t.Fatalf("insert fixture: %s", err)
+				}
+			})
+
+			if err := migrateDB(dbPath, _schemaVersion); err != nil {
+				t.Fatal(err)
+			}
+			checkMigratedData(t, dbPath, _schemaVersion, fixture.version)
+			if err := migrateDB(dbPath, fixture.version); err != nil {
+				t.Fatal(err)
+			}
+			checkMigratedData(t, dbPath, fixture.version, fixture.version)
+			if err := migrateDB(dbPath, _schemaVersion); err != nil {
+				t.Fatal(err)
+			}
+			checkMigratedData(t, dbPath, _schemaVersion, fixture.version)
+
+			if err := migrateDB(dbPath, 0); err != nil {
+				t.Fatal(err)
+			}
+			withTestConn(t, dbPath, func(conn *sql.DB) {
+				var tables []string
+				rows, err := conn.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations';`)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer rows.Close()
+				for rows.Next() {
+					var name string
+					if err := rows.Scan(&name); err != nil {
+						t.Fatal(err)
+					}
+					tables = append(tables, name)
+				}
+				if len(tables) > 0 {
+					t.Errorf("tables left at schema version 0: %v", tables)
+				}
+			})
+			if err := migrateDB(dbPath, _schemaVersion); err != nil {
+				t.Fatal(err)
+			}
+			withTestConn(t, dbPath, func(conn *sql.DB) {
+				if n := countRows(t, conn, "mission"); n != 0 {
+					t.Errorf("%d missions after migrating up from schema version 0", n)
+				}
+				if _, err := conn.Exec(`INSERT INTO mission (player_id, mission_id, start_timestamp, complete_payload)
+					VALUES ('EI1', 'm1', 1000, ?);`, _fixturePayload); err != nil {
+					t.Errorf("insert into migrated schema: %s", err)
+				}
+			})
+
+			backups, err := listDatabaseBackups(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(backups) == 0 {
+				t.Error("no backups taken before migrations")
+			}
+		})
+	}
+}
+
+// checkMigratedData checks that the fixture inserted at fixtureVersion is
+// intact in a database now at version.
+func checkMigratedData(t *testing.T, dbPath string, version uint, fixtureVersion uint) {
+	t.Helper()
+	withTestConn(t, dbPath, func(conn *sql.DB) {
+		var current uint
+		var dirty bool
+		if err := conn.QueryRow(`SELECT version, dirty FROM schema_migrations;`).Scan(&current, &dirty); err != nil {
+			t.Fatal(err)
+		}
+		if current != version || dirty {
+			t.Fatalf("schema version %d (dirty %t), expected %d", current, dirty, version)
+		}
+
+		var startTimestamp float64
+		var payload []byte
+		if err := conn.QueryRow(`SELECT start_timestamp, complete_payload FROM mission
+			WHERE player_id = 'EI1' AND mission_id = 'm1';`).Scan(&startTimestamp, &payload); err != nil {
+			t.Fatalf("mission at schema version %d: %s", version, err)
+		}
+		if startTimestamp != 1000 || !bytes.Equal(payload, _fixturePayload) {
+			t.Errorf("mission at schema version %d is %v %q", version, startTimestamp, payload)
+		}
+
+		timestampColumn := "backed_up_at"
+		if version < 2 {
+			timestampColumn = "recorded_at"
+		}
+		var backedUpAt float64
+		if err := conn.QueryRow(`SELECT `+timestampColumn+`, payload FROM backup
+			WHERE player_id = 'EI1';`).Scan(&backedUpAt, &payload); err != nil {
+			t.Fatalf("backup at schema version %d: %s", version, err)
+		}
+		if backedUpAt != 2000 || !bytes.Equal(payload, _fixturePayload) {
+			t.Errorf("backup at schema version %d is %v %q", version, backedUpAt, payload)
+		}
+		if version >= 3 {
+			var authenticated bool
+			if err := conn.QueryRow(`SELECT payload_authenticated FROM backup;`).Scan(&authenticated); err != nil {
+				t.Fatal(err)
+			}
+			if !authenticated {
+				t.Errorf("backup at schema version %d is not authenticated", version)
+			}
+		}
+
+		if version >= 5 {
+			expected := 0
+			if fixtureVersion >= 5 {
+				expected = 1
+			}
+			if n := countRows(t, conn, "artifact_drop"); n != expected {
+				t.Errorf("%d drops at schema version %d, expected %d", n, version, expected)
+			}
+		}
+		if version >= 9 {
+			var codec string
+			if err := conn.QueryRow(`SELECT codec FROM mission;`).Scan(&codec); err != nil {
+				t.Fatal(err)
+			}
+			if codec != "gzip" {
+				t.Errorf("codec %s at schema version %d", codec, version)
+			}
+		}
+		if version >= 10 {
+			var indexed bool
+			if err := conn.QueryRow(`SELECT drops_indexed FROM mission;`).Scan(&indexed); err != nil {
+				t.Fatal(err)
+			}
+			if expected := fixtureVersion >= 5; indexed != expected {
+				t.Errorf("drops_indexed %t at schema version %d, expected %t", indexed, version, expected)
+			}
+		}
+	})
+}
+
+func TestMigrateRefusesDatabase(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		version uint
+		dirty   bool
+	}{
+		{"dirty", 8, true},
+		{"newer", _schemaVersion + 1, false},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			dbPath := filepath.Join(t.TempDir(), "data.db")
+			if err := migrateDB(dbPath, _schemaVersion); err != nil {
+				t.Fatal(err)
+			}
+			withTestConn(t, dbPath, func(conn *sql.DB) {
+				if _, err := conn.Exec(`UPDATE schema_migrations SET version = ?, dirty = ?;`,
+					test.version, test.dirty); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if err := migrateDB(dbPath, _schemaVersion); err == nil {
+				t.Error("migrated database, expected error")
+			}
+			if err := openDB(dbPath); err == nil {
+				CloseDB()
+				t.Error("opened database, expected error")
+			}
+			withTestConn(t, dbPath, func(conn *sql.DB) {
+				var version uint
+				var dirty bool
+				if err := conn.QueryRow(`SELECT version, dirty FROM schema_migrations;`).Scan(&version, &dirty); err != nil {
+					t.Fatal(err)
+				}
+				if version != test.version || dirty != test.dirty {
+					t.Errorf("schema version changed to %d (dirty %t)", version, dirty)
+				}
+			})
+		})
+	}
+}
+
+func withTestConn(t *testing.T, dbPath string, f func(conn *sql.DB)) {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on&_journal_mode=WAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f(conn)
+}
+
+func countRows(t *testing.T, conn *sql.DB, table string) int {
+	t.Helper()
+	var n int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM ` + table + `;`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
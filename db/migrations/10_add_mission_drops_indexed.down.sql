@@ -0,0 +1,2 @@
+DROP INDEX mission_drops_not_indexed;
+ALTER TABLE mission DROP COLUMN drops_indexed;
@@ -0,0 +1,10 @@
+-- Whether the drops of a mission have been put in artifact_drop. Missions
+-- without drops, or with payloads that can't be decoded, have no rows there
+-- but mustn't be decoded again every time the database is opened.
+ALTER TABLE mission ADD COLUMN drops_indexed INTEGER NOT NULL DEFAULT FALSE;
+UPDATE mission SET drops_indexed = TRUE
+WHERE EXISTS (
+    SELECT 1 FROM artifact_drop
+    WHERE artifact_drop.player_id = mission.player_id AND artifact_drop.mission_id = mission.mission_id
+);
+CREATE INDEX mission_drops_not_indexed ON mission(drops_indexed) WHERE NOT drops_indexed;
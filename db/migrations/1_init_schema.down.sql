@@ -0,0 +1,2 @@
+DROP TABLE mission;
+DROP TABLE backup;
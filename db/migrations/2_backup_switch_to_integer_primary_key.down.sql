@@ -0,0 +1,19 @@
+-- The original schema only holds one backup per player, so only the latest
+-- backup of each player is kept.
+
+CREATE TABLE backup_tmp (
+    player_id TEXT PRIMARY KEY,
+    recorded_at REAL NOT NULL,
+    payload BLOB NOT NULL
+);
+INSERT INTO backup_tmp (player_id, recorded_at, payload)
+    SELECT player_id, backed_up_at, payload
+    FROM backup AS b
+    WHERE id = (
+        SELECT id FROM backup
+        WHERE player_id = b.player_id
+        ORDER BY backed_up_at DESC, id DESC
+        LIMIT 1
+    );
+DROP TABLE backup;
+ALTER TABLE backup_tmp RENAME TO backup;
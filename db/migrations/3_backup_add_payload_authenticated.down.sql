@@ -0,0 +1,5 @@
+-- Versions before this migration decode every backup as an authenticated
+-- message, so unauthenticated backups would be misread and are dropped.
+
+DELETE FROM backup WHERE NOT payload_authenticated;
+ALTER TABLE backup DROP COLUMN payload_authenticated;
@@ -0,0 +1,2 @@
+DROP INDEX artifacts_configuration_client_version_fetched_at;
+DROP TABLE artifacts_configuration;
@@ -0,0 +1,11 @@
+-- Cached /ei_afx/config responses. The configuration depends on the client
+-- version we claim to be, so cached entries are only valid for the same client
+-- version.
+CREATE TABLE artifacts_configuration (
+    id INTEGER PRIMARY KEY,
+    client_version INTEGER NOT NULL,
+    fetched_at REAL NOT NULL,
+    payload BLOB NOT NULL
+);
+CREATE INDEX artifacts_configuration_client_version_fetched_at
+    ON artifacts_configuration(client_version, fetched_at);
@@ -0,0 +1,2 @@
+DROP INDEX artifact_drop_player_id_artifact_family;
+DROP TABLE artifact_drop;
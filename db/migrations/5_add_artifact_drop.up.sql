@@ -0,0 +1,24 @@
+-- Index of items dropped by stored missions, so that drops can be queried
+-- without decoding every mission payload. Ship, duration type, level and
+-- duration of the mission are denormalized into each row for the same reason.
+-- The index is populated from payloads in Go code, including for missions
+-- stored before this migration.
+CREATE TABLE artifact_drop (
+    player_id TEXT NOT NULL,
+    mission_id TEXT NOT NULL,
+    drop_index INTEGER NOT NULL,
+    start_timestamp REAL NOT NULL,
+    duration_seconds REAL NOT NULL,
+    ship INTEGER NOT NULL,
+    duration_type INTEGER NOT NULL,
+    level INTEGER NOT NULL,
+    artifact_name INTEGER NOT NULL,
+    artifact_level INTEGER NOT NULL,
+    artifact_rarity INTEGER NOT NULL,
+    artifact_family INTEGER NOT NULL,
+    artifact_tier INTEGER NOT NULL,
+    PRIMARY KEY (player_id, mission_id, drop_index),
+    FOREIGN KEY (player_id, mission_id) REFERENCES mission(player_id, mission_id) ON DELETE CASCADE
+);
+CREATE INDEX artifact_drop_player_id_artifact_family
+    ON artifact_drop(player_id, artifact_family, start_timestamp);
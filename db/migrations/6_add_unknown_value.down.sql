@@ -0,0 +1 @@
+DROP TABLE unknown_value;
@@ -0,0 +1,13 @@
+-- Values in stored missions that the app version that last synced couldn't
+-- handle, e.g. ships or artifacts added in a game update, and fields missing
+-- from ei.proto. Rows are replaced on every sync of the player, so values
+-- handled by a newer version of the app disappear.
+CREATE TABLE unknown_value (
+    player_id TEXT NOT NULL,
+    mission_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    value TEXT NOT NULL,
+    first_seen_timestamp REAL NOT NULL,
+    PRIMARY KEY (player_id, mission_id, kind, value),
+    FOREIGN KEY (player_id, mission_id) REFERENCES mission(player_id, mission_id) ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+-- Versions before this migration would take imported missions for ones
+-- fetched from the server and never replace them, so they are dropped; they
+-- can be imported again after upgrading.
+
+DELETE FROM mission WHERE imported;
+ALTER TABLE mission DROP COLUMN imported;
@@ -0,0 +1,4 @@
+-- Missions imported from spreadsheets or other tools rather than fetched from
+-- the game server. Their payloads are reconstructed, and are replaced when the
+-- server version of the mission is fetched.
+ALTER TABLE mission ADD COLUMN imported INTEGER NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+DROP TABLE quarantined_backup;
+DROP TABLE quarantined_mission;
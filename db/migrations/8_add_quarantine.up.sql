@@ -0,0 +1,22 @@
+-- Rows found corrupt by the integrity check are moved here rather than
+-- deleted, in case they can be salvaged by hand. Quarantined missions are no
+-- longer considered stored, so they are fetched again on the next sync if the
+-- server still has them.
+CREATE TABLE quarantined_mission (
+    player_id TEXT NOT NULL,
+    mission_id TEXT NOT NULL,
+    start_timestamp REAL,
+    complete_payload BLOB,
+    imported INTEGER NOT NULL DEFAULT FALSE,
+    problem TEXT NOT NULL,
+    quarantined_at REAL NOT NULL
+);
+CREATE TABLE quarantined_backup (
+    id INTEGER PRIMARY KEY,
+    player_id TEXT NOT NULL,
+    backed_up_at REAL,
+    payload BLOB,
+    payload_authenticated INTEGER NOT NULL DEFAULT FALSE,
+    problem TEXT NOT NULL,
+    quarantined_at REAL NOT NULL
+);
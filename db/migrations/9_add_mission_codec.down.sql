@@ -0,0 +1,5 @@
+-- MigrateDatabase converts zstd payloads back to gzip before running this.
+DROP INDEX mission_codec;
+ALTER TABLE quarantined_mission DROP COLUMN codec;
+ALTER TABLE mission DROP COLUMN codec;
+DROP TABLE compression_dictionary;
@@ -0,0 +1,13 @@
+-- Mission payloads used to be gzipped one by one. They are small and very
+-- similar to each other, so they compress much better with zstd and a
+-- dictionary trained on stored payloads. codec is gzip or zstd; zstd frames
+-- name the dictionary they were compressed with, which must never be deleted
+-- while payloads still use it.
+CREATE TABLE compression_dictionary (
+    id INTEGER PRIMARY KEY,
+    created_at REAL NOT NULL,
+    dictionary BLOB NOT NULL
+);
+ALTER TABLE mission ADD COLUMN codec TEXT NOT NULL DEFAULT 'gzip';
+CREATE INDEX mission_codec ON mission(codec);
+ALTER TABLE quarantined_mission ADD COLUMN codec TEXT NOT NULL DEFAULT 'gzip';
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// UnknownValue is a value unknown to the app recorded for a stored mission.
+type UnknownValue struct {
+	MissionId          string
+	FirstSeenTimestamp float64
+	ei.UnknownValue
+}
+
+// ReplaceUnknownValues replaces the recorded unknown values of the player's
+// missions with values, keyed by mission id, keeping the time each value was
+// first seen. Values not recorded before are returned.
+func ReplaceUnknownValues(playerId string, timestamp float64, values map[string][]ei.UnknownValue) ([]*UnknownValue, error) {
+	action := fmt.Sprintf("record unknown values for player %s in database", playerId)
+	type key struct {
+		missionId string
+		value     ei.UnknownValue
+	}
+	var newValues []*UnknownValue
+	err := transact(action, func(tx *sql.Tx) error {
+		firstSeen := make(map[key]float64)
+		rows, err := tx.Query(`SELECT mission_id, kind, value, first_seen_timestamp FROM unknown_value
+			WHERE player_id = ?;`, playerId)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var k key
+			var t float64
+			if err := rows.Scan(&k.missionId, &k.value.Kind, &k.value.Value, &t); err != nil {
+				return err
+			}
+			firstSeen[k] = t
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM unknown_value WHERE player_id = ?;`, playerId)
+		if err != nil {
+			return err
+		}
+		for missionId, missionValues := range values {
+			for _, v := range missionValues {
+				t, seen := firstSeen[key{missionId, v}]
+				if !seen {
+					t = timestamp
+					newValues = append(newValues, &UnknownValue{
+						MissionId:          missionId,
+						FirstSeenTimestamp: t,
+						UnknownValue:       v,
+					})
+				}
+				_, err := tx.Exec(`INSERT OR IGNORE INTO
+					unknown_value(player_id, mission_id, kind, value, first_seen_timestamp)
+					VALUES (?, ?, ?, ?, ?);`,
+					playerId, missionId, v.Kind, v.Value, t)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newValues, nil
+}
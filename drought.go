@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// dropTarget describes the items a drought or streak is about: a rarity,
+// optionally of a single family. Stone fragments belong to the family of the
+// corresponding stone.
+type dropTarget struct {
+	Family *ei.ArtifactSpec_Name
+	Rarity ei.ArtifactSpec_Rarity
+}
+
+func (t dropTarget) matches(a *ei.ArtifactSpec) bool {
+	if a.GetRarity() != t.Rarity {
+		return false
+	}
+	return t.Family == nil || a.Family() == *t.Family
+}
+
+func (t dropTarget) Display() string {
+	if t.Family == nil {
+		return fmt.Sprintf("%s (any item)", t.Rarity.Display())
+	}
+	return fmt.Sprintf("%s %s", t.Rarity.Display(), t.Family.CasedName())
+}
+
+func (t dropTarget) less(other dropTarget) bool {
+	if (t.Family == nil) != (other.Family == nil) {
+		return t.Family == nil
+	}
+	if t.Rarity != other.Rarity {
+		return t.Rarity > other.Rarity
+	}
+	if t.Family == nil {
+		return false
+	}
+	return t.Family.CasedName() < other.Family.CasedName()
+}
+
+// droughtScope is the set of missions considered: either all missions, or
+// those of a single ship and duration type.
+type droughtScope struct {
+	All bool
+	shipDurationKey
+}
+
+func (s droughtScope) includes(m *mission) bool {
+	return s.All || (m.Ship == s.Ship && m.DurationType == s.DurationType)
+}
+
+func (s droughtScope) Display() string {
+	if s.All {
+		return "All missions"
+	}
+	return fmt.Sprintf("%s (%s)", s.Ship.Name(), s.DurationType.Display())
+}
+
+// droughtStats tracks droughts, i.e. consecutive missions without a matching
+// drop, and streaks, i.e. consecutive missions each with at least one matching
+// drop. Droughts in time are measured between mission returns; the current
+// drought extends to now, as passed to finish.
+type droughtStats struct {
+	Scope                  droughtScope
+	Target                 dropTarget
+	Missions               int
+	Matches                int
+	CurrentDrought         int
+	CurrentDroughtDuration time.Duration
+	LongestDrought         int
+	LongestDroughtDuration time.Duration
+	CurrentStreak          int
+	LongestStreak          int
+	// LastSeen is the return time of the last mission with a matching drop, or
+	// zero if there's none.
+	LastSeen time.Time
+
+	droughtStart time.Time
+}
+
+// newDroughtStats starts tracking droughts and streaks of the target in the
+// scope. Missions are then added in chronological order, and finish is called
+// after the last one.
+func newDroughtStats(scope droughtScope, target dropTarget) *droughtStats {
+	return &droughtStats{Scope: scope, Target: target}
+}
+
+// add updates the stats with the next mission, ignoring missions outside the
+// scope.
+func (s *droughtStats) add(m *mission) {
+	if !s.Scope.includes(m) {
+		return
+	}
+	if s.Missions == 0 {
+		s.droughtStart = m.LaunchedAt
+	}
+	s.Missions++
+	matched := false
+	for _, a := range m.Artifacts {
+		if s.Target.matches(a) {
+			s.Matches++
+			matched = true
+		}
+	}
+	if matched {
+		if d := m.ReturnedAt.Sub(s.droughtStart); d > s.LongestDroughtDuration {
+			s.LongestDroughtDuration = d
+		}
+		s.droughtStart = m.ReturnedAt
+		s.LastSeen = m.ReturnedAt
+		s.CurrentDrought = 0
+		s.CurrentStreak++
+		if s.CurrentStreak > s.LongestStreak {
+			s.LongestStreak = s.CurrentStreak
+		}
+	} else {
+		s.CurrentStreak = 0
+		s.CurrentDrought++
+		if s.CurrentDrought > s.LongestDrought {
+			s.LongestDrought = s.CurrentDrought
+		}
+	}
+}
+
+// finish extends the current drought to now, which needn't be the current
+// time, e.g. to keep exports reproducible.
+func (s *droughtStats) finish(now time.Time) {
+	if s.Missions > 0 {
+		s.CurrentDroughtDuration = now.Sub(s.droughtStart)
+		if s.CurrentDroughtDuration > s.LongestDroughtDuration {
+			s.LongestDroughtDuration = s.CurrentDroughtDuration
+		}
+	}
+}
+
+// droughtSummarizer computes drought and streak statistics for all missions
+// and for each ship and duration type, for drops of each rarity above common,
+// and for each family and rarity combination that has dropped before in the
+// scope. It takes two passes over the missions, since the targets need to be
+// known before droughts can be tracked: every mission is prepared first, then
+// added in chronological order.
+type droughtSummarizer struct {
+	scopes  []droughtScope
+	targets map[droughtScope]*droughtTargetSet
+	// stats are grouped by scope, so that missions are only added to stats
+	// of scopes including them.
+	stats   []*droughtStats
+	byScope map[droughtScope][]*droughtStats
+	// lastReturnedAt is the latest return time of the missions added.
+	lastReturnedAt time.Time
+}
+
+func newDroughtSummarizer() *droughtSummarizer {
+	all := droughtScope{All: true}
+	return &droughtSummarizer{
+		scopes:  []droughtScope{all},
+		targets: map[droughtScope]*droughtTargetSet{all: newDroughtTargetSet()},
+	}
+}
+
+func (s *droughtSummarizer) prepare(m *mission) {
+	scope := droughtScope{shipDurationKey: shipDurationKey{m.Ship, m.DurationType}}
+	targets, ok := s.targets[scope]
+	if !ok {
+		targets = newDroughtTargetSet()
+		s.targets[scope] = targets
+		s.scopes = append(s.scopes, scope)
+	}
+	targets.add(m)
+	s.targets[droughtScope{All: true}].add(m)
+}
+
+func (s *droughtSummarizer) add(m *mission) {
+	// The first mission added ends the preparation pass.
+	if s.byScope == nil {
+		sort.SliceStable(s.scopes, func(i, j int) bool {
+			if s.scopes[i].All != s.scopes[j].All {
+				return s.scopes[i].All
+			}
+			return s.scopes[i].less(s.scopes[j].shipDurationKey)
+		})
+		s.byScope = make(map[droughtScope][]*droughtStats)
+		for _, scope := range s.scopes {
+			for _, target := range s.targets[scope].sorted() {
+				stats := newDroughtStats(scope, target)
+				s.stats = append(s.stats, stats)
+				s.byScope[scope] = append(s.byScope[scope], stats)
+			}
+		}
+	}
+	if m.ReturnedAt.After(s.lastReturnedAt) {
+		s.lastReturnedAt = m.ReturnedAt
+	}
+	for _, stats := range s.byScope[droughtScope{All: true}] {
+		stats.add(m)
+	}
+	for _, stats := range s.byScope[droughtScope{shipDurationKey: shipDurationKey{m.Ship, m.DurationType}}] {
+		stats.add(m)
+	}
+}
+
+// summary extends current droughts to the return of the last mission rather
+// than to now, so that exports of the same missions are identical.
+func (s *droughtSummarizer) summary() []*droughtStats {
+	for _, stats := range s.stats {
+		stats.finish(s.lastReturnedAt)
+	}
+	return s.stats
+}
+
+// droughtTargetSet collects the family and rarity combinations dropped in a
+// scope.
+type droughtTargetSet struct {
+	seen    map[familyRarity]struct{}
+	targets []dropTarget
+}
+
+type familyRarity struct {
+	family ei.ArtifactSpec_Name
+	rarity ei.ArtifactSpec_Rarity
+}
+
+func newDroughtTargetSet() *droughtTargetSet {
+	return &droughtTargetSet{seen: make(map[familyRarity]struct{})}
+}
+
+func (t *droughtTargetSet) add(m *mission) {
+	for _, a := range m.Artifacts {
+		key := familyRarity{a.Family(), a.GetRarity()}
+		if _, exists := t.seen[key]; !exists {
+			family := key.family
+			t.targets = append(t.targets, dropTarget{Family: &family, Rarity: key.rarity})
+			t.seen[key] = struct{}{}
+		}
+	}
+}
+
+// sorted returns the targets of each rarity above common, followed by the
+// family and rarity combinations.
+func (t *droughtTargetSet) sorted() []dropTarget {
+	targets := []dropTarget{
+		{Rarity: ei.ArtifactSpec_LEGENDARY},
+		{Rarity: ei.ArtifactSpec_EPIC},
+		{Rarity: ei.ArtifactSpec_RARE},
+	}
+	familyTargets := append([]dropTarget(nil), t.targets...)
+	sort.SliceStable(familyTargets, func(i, j int) bool {
+		return familyTargets[i].less(familyTargets[j])
+	})
+	return append(targets, familyTargets...)
+}
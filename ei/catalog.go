@@ -0,0 +1,281 @@
+package ei
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The artifact catalog describes every artifact family: its name, type, tier
+// names, and for stone fragments, the corresponding stone. It is embedded in
+// the app and versioned, and can be extended or corrected without a new
+// release through an override file in the same format; entries of the override
+// replace embedded entries with the same id.
+
+//go:embed catalog.json
+var _embeddedCatalog []byte
+
+type Catalog struct {
+	// Version is bumped whenever the embedded catalog changes. An override
+	// older than the embedded catalog is ignored, since the app has caught up.
+	Version   int                `json:"version"`
+	Artifacts []*CatalogArtifact `json:"artifacts"`
+}
+
+type CatalogArtifact struct {
+	// Id is the ArtifactSpec_Name enum name, e.g. ORNATE_GUSSET.
+	Id string `json:"id"`
+	// Value is the enum value, only needed for artifacts added to the game
+	// after the app was built, whose ids aren't known to the app.
+	Value *int32 `json:"value,omitempty"`
+	// Name is the in-game name of the family, in all caps.
+	Name string `json:"name"`
+	// Type is the ArtifactSpec_Type enum name.
+	Type string `json:"type"`
+	// Stone is the id of the corresponding stone of a stone fragment.
+	Stone string `json:"stone,omitempty"`
+	// Unconfirmed marks items that exist in the game data but have never been
+	// seen in the game, and thus have no known tiers.
+	Unconfirmed bool `json:"unconfirmed,omitempty"`
+	// Tiers are indexed by ArtifactSpec_Level. Stone fragments have a single
+	// tier regardless of level.
+	Tiers []*CatalogTier `json:"tiers,omitempty"`
+}
+
+type CatalogTier struct {
+	// TierName is the in-game tier name, in all caps, e.g. EGGCEPTIONAL.
+	TierName string `json:"tier_name"`
+	// Name is the full in-game name of the item, in all caps.
+	Name string `json:"name"`
+}
+
+type catalogEntry struct {
+	*CatalogArtifact
+	typ ArtifactSpec_Type
+}
+
+var (
+	_catalogVersion int
+	_catalog        map[ArtifactSpec_Name]*catalogEntry
+	// _stones maps stone fragments to their corresponding stones, and
+	// _fragments the other way around.
+	_stones    map[ArtifactSpec_Name]ArtifactSpec_Name
+	_fragments map[ArtifactSpec_Name]ArtifactSpec_Name
+)
+
+func init() {
+	// A broken embedded catalog is caught by the tests of this package; should
+	// one slip through anyway, names fall back to enum ids rather than keeping
+	// the app from starting.
+	c, err := ParseCatalog(_embeddedCatalog)
+	if err != nil {
+		log.Errorf("embedded artifact catalog: %s", err)
+		setCatalog(0, nil)
+		return
+	}
+	entries, err := resolveCatalog(c.Artifacts)
+	if err != nil {
+		log.Errorf("embedded artifact catalog: %s", err)
+		setCatalog(0, nil)
+		return
+	}
+	if missing := missingFromCatalog(entries); len(missing) > 0 {
+		log.Errorf("embedded artifact catalog: missing %s", strings.Join(missing, ", "))
+	}
+	setCatalog(c.Version, entries)
+}
+
+func ParseCatalog(data []byte) (*Catalog, error) {
+	c := &Catalog{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// CatalogVersion returns the version of the catalog in use, which is that of
+// the override if one is loaded.
+func CatalogVersion() int {
+	return _catalogVersion
+}
+
+// LoadCatalogOverride loads the override catalog at path, if it exists. An
+// override older than the embedded catalog is ignored with an error.
+func LoadCatalogOverride(path string) error {
+	action := fmt.Sprintf("load artifact catalog override %s", path)
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return wrap(err)
+	}
+	override, err := ParseCatalog(data)
+	if err != nil {
+		return wrap(err)
+	}
+	if override.Version < _catalogVersion {
+		return wrap(errors.Errorf("override version %d is older than built-in version %d, ignored",
+			override.Version, _catalogVersion))
+	}
+	overrideEntries, err := resolveCatalog(override.Artifacts)
+	if err != nil {
+		return wrap(err)
+	}
+	entries := make(map[ArtifactSpec_Name]*catalogEntry)
+	for name, e := range _catalog {
+		entries[name] = e
+	}
+	for name, e := range overrideEntries {
+		entries[name] = e
+	}
+	setCatalog(override.Version, entries)
+	return nil
+}
+
+// resolveCatalog validates catalog artifacts and resolves their ids.
+func resolveCatalog(artifacts []*CatalogArtifact) (map[ArtifactSpec_Name]*catalogEntry, error) {
+	ids := make(map[string]ArtifactSpec_Name)
+	for _, a := range artifacts {
+		if a.Value != nil {
+			ids[a.Id] = ArtifactSpec_Name(*a.Value)
+		} else if value, ok := ArtifactSpec_Name_value[a.Id]; ok {
+			ids[a.Id] = ArtifactSpec_Name(value)
+		} else {
+			return nil, errors.Errorf("unknown artifact id %#v without value", a.Id)
+		}
+	}
+	resolveId := func(id string) (ArtifactSpec_Name, bool) {
+		if name, ok := ids[id]; ok {
+			return name, true
+		}
+		if value, ok := ArtifactSpec_Name_value[id]; ok {
+			return ArtifactSpec_Name(value), true
+		}
+		return ArtifactSpec_UNKNOWN, false
+	}
+
+	entries := make(map[ArtifactSpec_Name]*catalogEntry)
+	for _, a := range artifacts {
+		name := ids[a.Id]
+		if _, exists := entries[name]; exists {
+			return nil, errors.Errorf("duplicate artifact %s", a.Id)
+		}
+		typ, ok := ArtifactSpec_Type_value[a.Type]
+		if !ok {
+			return nil, errors.Errorf("%s: unknown type %#v", a.Id, a.Type)
+		}
+		if a.Name == "" {
+			return nil, errors.Errorf("%s: name is empty", a.Id)
+		}
+		if (ArtifactSpec_Type(typ) == ArtifactSpec_STONE_INGREDIENT) != (a.Stone != "") {
+			return nil, errors.Errorf("%s: stone must be set for and only for stone fragments", a.Id)
+		}
+		if a.Stone != "" {
+			if _, ok := resolveId(a.Stone); !ok {
+				return nil, errors.Errorf("%s: unknown stone %#v", a.Id, a.Stone)
+			}
+		}
+		if !a.Unconfirmed && len(a.Tiers) == 0 {
+			return nil, errors.Errorf("%s: no tiers", a.Id)
+		}
+		entries[name] = &catalogEntry{
+			CatalogArtifact: a,
+			typ:             ArtifactSpec_Type(typ),
+		}
+	}
+	return entries, nil
+}
+
+// missingFromCatalog returns the ids of known artifacts not in the catalog.
+func missingFromCatalog(entries map[ArtifactSpec_Name]*catalogEntry) []string {
+	var missing []string
+	for value, id := range ArtifactSpec_Name_name {
+		name := ArtifactSpec_Name(value)
+		if name == ArtifactSpec_UNKNOWN {
+			continue
+		}
+		if _, ok := entries[name]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func setCatalog(version int, entries map[ArtifactSpec_Name]*catalogEntry) {
+	stones := make(map[ArtifactSpec_Name]ArtifactSpec_Name)
+	fragments := make(map[ArtifactSpec_Name]ArtifactSpec_Name)
+	for name, e := range entries {
+		if e.Stone == "" {
+			continue
+		}
+		var stone ArtifactSpec_Name
+		if value, ok := ArtifactSpec_Name_value[e.Stone]; ok {
+			stone = ArtifactSpec_Name(value)
+		} else {
+			for other, oe := range entries {
+				if oe.Id == e.Stone {
+					stone = other
+				}
+			}
+		}
+		stones[name] = stone
+		fragments[stone] = name
+	}
+	_catalogVersion = version
+	_catalog = entries
+	_stones = stones
+	_fragments = fragments
+}
+
+// KnownArtifactNames returns all artifacts in the catalog, including those
+// only known from the override, in enum order.
+func KnownArtifactNames() []ArtifactSpec_Name {
+	var names []ArtifactSpec_Name
+	for name := range _catalog {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Id returns the enum name of the artifact, which is the catalog id for
+// artifacts unknown to this build.
+func (a ArtifactSpec_Name) Id() string {
+	if _, ok := ArtifactSpec_Name_name[int32(a)]; ok {
+		return a.String()
+	}
+	if e, ok := _catalog[a]; ok {
+		return e.Id
+	}
+	return strconv.Itoa(int(a))
+}
+
+// catalogTier returns the catalog tier of the item, or nil if unknown.
+func (a *ArtifactSpec) catalogTier() *CatalogTier {
+	e, ok := _catalog[a.GetName()]
+	if !ok || len(e.Tiers) == 0 {
+		return nil
+	}
+	if e.typ == ArtifactSpec_STONE_INGREDIENT {
+		return e.Tiers[0]
+	}
+	level := int(a.GetLevel())
+	if level >= len(e.Tiers) {
+		return nil
+	}
+	return e.Tiers[level]
+}
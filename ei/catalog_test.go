@@ -0,0 +1,46 @@
+package ei
+
+import "testing"
+
+func TestEmbeddedCatalog(t *testing.T) {
+	c, err := ParseCatalog(_embeddedCatalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := resolveCatalog(c.Artifacts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing := missingFromCatalog(entries); len(missing) > 0 {
+		t.Errorf("artifacts missing from the embedded catalog: %v", missing)
+	}
+	if c.Version != CatalogVersion() {
+		t.Errorf("catalog version in use is %d, expected embedded version %d", CatalogVersion(), c.Version)
+	}
+}
+
+func TestResolveCatalogErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		catalog string
+	}{
+		{"unknown id", `{"artifacts": [{"id": "NOT_AN_ARTIFACT", "name": "X", "type": "ARTIFACT", "tiers": [{}]}]}`},
+		{"unknown type", `{"artifacts": [{"id": "ORNATE_GUSSET", "name": "GUSSET", "type": "GUSSET", "tiers": [{}]}]}`},
+		{"no name", `{"artifacts": [{"id": "ORNATE_GUSSET", "type": "ARTIFACT", "tiers": [{}]}]}`},
+		{"no tiers", `{"artifacts": [{"id": "ORNATE_GUSSET", "name": "GUSSET", "type": "ARTIFACT"}]}`},
+		{"fragment without stone", `{"artifacts": [{"id": "TACHYON_STONE_FRAGMENT", "name": "FRAGMENT", "type": "STONE_INGREDIENT", "tiers": [{}]}]}`},
+		{"duplicate", `{"artifacts": [
+			{"id": "ORNATE_GUSSET", "name": "GUSSET", "type": "ARTIFACT", "tiers": [{}]},
+			{"id": "ORNATE_GUSSET", "name": "GUSSET", "type": "ARTIFACT", "tiers": [{}]}]}`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := ParseCatalog([]byte(test.catalog))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := resolveCatalog(c.Artifacts); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package ei
+
+func (e Egg) Display() string {
+	switch e {
+	case Egg_EDIBLE:
+		return "Edible"
+	case Egg_SUPERFOOD:
+		return "Superfood"
+	case Egg_MEDICAL:
+		return "Medical"
+	case Egg_ROCKET_FUEL:
+		return "Rocket Fuel"
+	case Egg_SUPER_MATERIAL:
+		return "Super Material"
+	case Egg_FUSION:
+		return "Fusion"
+	case Egg_QUANTUM:
+		return "Quantum"
+	case Egg_IMMORTALITY:
+		return "Immortality"
+	case Egg_TACHYON:
+		return "Tachyon"
+	case Egg_GRAVITON:
+		return "Graviton"
+	case Egg_DILITHIUM:
+		return "Dilithium"
+	case Egg_PRODIGY:
+		return "Prodigy"
+	case Egg_TERRAFORM:
+		return "Terraform"
+	case Egg_ANTIMATTER:
+		return "Antimatter"
+	case Egg_DARK_MATTER:
+		return "Dark Matter"
+	case Egg_AI:
+		return "AI"
+	case Egg_NEBULA:
+		return "Nebula"
+	case Egg_UNIVERSE:
+		return "Universe"
+	case Egg_ENLIGHTENMENT:
+		return "Enlightenment"
+	case Egg_CHOCOLATE:
+		return "Chocolate"
+	case Egg_EASTER:
+		return "Easter"
+	case Egg_WATERBALLOON:
+		return "Water Balloon"
+	case Egg_FIREWORK:
+		return "Firework"
+	case Egg_PUMPKIN:
+		return "Pumpkin"
+	}
+	return unknownDisplay(int32(e))
+}
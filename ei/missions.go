@@ -25,7 +25,7 @@ func (s MissionInfo_Spaceship) Name() string {
 	case MissionInfo_HENERPRISE:
 		return "Henerprise"
 	}
-	return "Unknown"
+	return unknownDisplay(int32(s))
 }
 
 func (d MissionInfo_DurationType) Display() string {
@@ -39,7 +39,46 @@ func (d MissionInfo_DurationType) Display() string {
 	case MissionInfo_EPIC:
 		return "Extended"
 	}
-	return "Unknown"
+	return unknownDisplay(int32(d))
+}
+
+// LaunchPoints is how much a launch of this duration counts towards the next
+// star level of its ship, the unit of level_mission_requirements in the
+// artifacts configuration. Tutorial and unknown durations count as short.
+//
+// The server doesn't publish these weights; they are the values commonly cited
+// by players, not confirmed by any payload. The client's own tally,
+// ArtifactsClientInfo.LaunchCounts, is only sent along with mission requests,
+// not in backups, so launch points derived from stored missions, and the
+// projections built on them, are approximate.
+func (d MissionInfo_DurationType) LaunchPoints() float64 {
+	switch d {
+	case MissionInfo_LONG:
+		return 1.4
+	case MissionInfo_EPIC:
+		return 1.8
+	}
+	return 1
+}
+
+func (s MissionInfo_Status) Display() string {
+	switch s {
+	case MissionInfo_FUELING:
+		return "Fueling"
+	case MissionInfo_PREPARE_TO_LAUNCH:
+		return "Preparing to launch"
+	case MissionInfo_EXPLORING:
+		return "Exploring"
+	case MissionInfo_RETURNED:
+		return "Returned"
+	case MissionInfo_ANALYZING:
+		return "Analyzing"
+	case MissionInfo_COMPLETE:
+		return "Complete"
+	case MissionInfo_ARCHIVED:
+		return "Archived"
+	}
+	return unknownDisplay(int32(s))
 }
 
 func (fc *EggIncFirstContactResponse) GetCompletedMissions() []*MissionInfo {
@@ -64,3 +103,59 @@ func (fc *EggIncFirstContactResponse) GetCompletedMissions() []*MissionInfo {
 	})
 	return completed
 }
+
+// GetInProgressMissions returns missions that have been launched but not yet
+// collected, i.e. ships that are still exploring or have returned but haven't
+// been opened yet, in chronological order.
+func (fc *EggIncFirstContactResponse) GetInProgressMissions() []*MissionInfo {
+	afxdb := fc.GetBackup().GetArtifactsDb()
+	var inProgress []*MissionInfo
+	seen := make(map[string]struct{})
+	for _, mission := range afxdb.GetMissionInfos() {
+		status := mission.GetStatus()
+		if status == MissionInfo_EXPLORING || status == MissionInfo_RETURNED {
+			id := mission.GetIdentifier()
+			if _, exists := seen[id]; !exists {
+				inProgress = append(inProgress, mission)
+				seen[id] = struct{}{}
+			}
+		}
+	}
+	sort.SliceStable(inProgress, func(i, j int) bool {
+		return inProgress[i].GetStartTimeDerived() < inProgress[j].GetStartTimeDerived()
+	})
+	return inProgress
+}
+
+// GetShipParameters returns the mission parameters for the ship in the
+// artifacts configuration, or nil if not found.
+func (c *ArtifactsConfigurationResponse) GetShipParameters(ship MissionInfo_Spaceship) *ArtifactsConfigurationResponse_MissionParameters {
+	for _, p := range c.GetMissionParameters() {
+		if p.GetShip() == ship {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetDurationParameters returns the parameters for the ship and duration type
+// in the artifacts configuration, or nil if not found.
+func (c *ArtifactsConfigurationResponse) GetDurationParameters(ship MissionInfo_Spaceship, durationType MissionInfo_DurationType) *ArtifactsConfigurationResponse_MissionParameters_Duration {
+	for _, d := range c.GetShipParameters(ship).GetDurations() {
+		if d.GetDurationType() == durationType {
+			return d
+		}
+	}
+	return nil
+}
+
+// CapacityAtLevel returns the base capacity of a mission launched at the star
+// level, without capacity boosting research.
+func (d *ArtifactsConfigurationResponse_MissionParameters_Duration) CapacityAtLevel(level uint32) uint32 {
+	return d.GetCapacity() + level*d.GetLevelCapacityBump()
+}
+
+// QualityAtLevel returns the quality of a mission launched at the star level.
+func (d *ArtifactsConfigurationResponse_MissionParameters_Duration) QualityAtLevel(level uint32) float64 {
+	return float64(d.GetQuality()) + float64(level)*float64(d.GetLevelQualityBump())
+}
@@ -0,0 +1,31 @@
+package ei
+
+func (t RewardType) Display() string {
+	switch t {
+	case RewardType_CASH:
+		return "Cash"
+	case RewardType_GOLD:
+		return "Golden Eggs"
+	case RewardType_SOUL_EGGS:
+		return "Soul Eggs"
+	case RewardType_EGGS_OF_PROPHECY:
+		return "Eggs of Prophecy"
+	case RewardType_EPIC_RESEARCH_ITEM:
+		return "Epic Research"
+	case RewardType_PIGGY_FILL:
+		return "Piggy Fill"
+	case RewardType_PIGGY_MULTIPLIER:
+		return "Piggy Multiplier"
+	case RewardType_PIGGY_LEVEL_BUMP:
+		return "Piggy Level Bump"
+	case RewardType_BOOST:
+		return "Boost"
+	case RewardType_BOOST_TOKEN:
+		return "Boost Token"
+	case RewardType_ARTIFACT:
+		return "Artifact"
+	case RewardType_ARTIFACT_CASE:
+		return "Artifact Case"
+	}
+	return unknownDisplay(int32(t))
+}
@@ -0,0 +1,280 @@
+package ei
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ei.proto is proto2, where enums are closed: a conforming decoder keeps a
+// value unknown to this build, say a ship added in a game update, in the
+// unknown fields of the message and leaves the enum field unset. The Go decoder
+// currently stores such values in the field anyway, but RecoverUnknownEnums
+// makes sure of it, so that they show up as "Unknown (n)" (or through the
+// catalog override, for artifacts) instead of silently turning into the
+// default value.
+
+func unknownDisplay(value int32) string {
+	return fmt.Sprintf("Unknown (%d)", value)
+}
+
+// RecoverUnknownEnums moves enum values unknown to this build from the unknown
+// fields of m and its submessages back into the enum fields they belong to.
+// Recovered values of repeated fields are appended after known values.
+func RecoverUnknownEnums(m proto.Message) {
+	recoverUnknownEnums(m.ProtoReflect())
+}
+
+func recoverUnknownEnums(m protoreflect.Message) {
+	if unknown := m.GetUnknown(); len(unknown) > 0 {
+		fields := m.Descriptor().Fields()
+		var rest protoreflect.RawFields
+		b := []byte(unknown)
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				rest = append(rest, b...)
+				break
+			}
+			vn := protowire.ConsumeFieldValue(num, typ, b[n:])
+			if vn < 0 {
+				rest = append(rest, b...)
+				break
+			}
+			field := b[:n+vn]
+			b = b[n+vn:]
+			fd := fields.ByNumber(num)
+			if fd == nil || fd.Kind() != protoreflect.EnumKind || !recoverEnumField(m, fd, typ, field[n:]) {
+				rest = append(rest, field...)
+			}
+		}
+		m.SetUnknown(rest)
+	}
+	rangeSubmessages(m, recoverUnknownEnums)
+}
+
+// recoverEnumField sets the enum field fd of m from its encoded value, and
+// reports whether it did.
+func recoverEnumField(m protoreflect.Message, fd protoreflect.FieldDescriptor, typ protowire.Type, b []byte) bool {
+	var values []protoreflect.EnumNumber
+	switch {
+	case typ == protowire.VarintType:
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return false
+		}
+		values = append(values, protoreflect.EnumNumber(int32(v)))
+	case typ == protowire.BytesType && fd.IsList():
+		// Packed repeated field.
+		packed, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return false
+		}
+		for len(packed) > 0 {
+			v, n := protowire.ConsumeVarint(packed)
+			if n < 0 {
+				return false
+			}
+			values = append(values, protoreflect.EnumNumber(int32(v)))
+			packed = packed[n:]
+		}
+	default:
+		return false
+	}
+	if fd.IsList() {
+		list := m.Mutable(fd).List()
+		for _, v := range values {
+			list.Append(protoreflect.ValueOfEnum(v))
+		}
+		return true
+	}
+	// A known value of the same field was also present; which one came last
+	// is lost, so keep the known one.
+	if m.Has(fd) {
+		return false
+	}
+	m.Set(fd, protoreflect.ValueOfEnum(values[0]))
+	return true
+}
+
+// rangeSubmessages calls f on every submessage set in m, including elements of
+// repeated and map fields.
+func rangeSubmessages(m protoreflect.Message, f func(protoreflect.Message)) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					f(mv.Message())
+					return true
+				})
+			}
+		case fd.IsList():
+			if fd.Message() != nil {
+				list := v.List()
+				for i := 0; i < list.Len(); i++ {
+					f(list.Get(i).Message())
+				}
+			}
+		case fd.Message() != nil:
+			f(v.Message())
+		}
+		return true
+	})
+}
+
+// UnknownField is a field present in a payload but not in ei.proto, i.e. added
+// to the game after this build.
+type UnknownField struct {
+	// Message is the full name of the message containing the field, e.g.
+	// ei.MissionInfo.
+	Message string
+	Number  int32
+	// WireType hints at the type of the field, e.g. BytesType for strings,
+	// bytes, submessages and packed repeated fields.
+	WireType protowire.Type
+}
+
+func (f UnknownField) String() string {
+	return fmt.Sprintf("%s.%d", f.Message, f.Number)
+}
+
+// WireTypeName returns the name of the wire type, e.g. varint.
+func (f UnknownField) WireTypeName() string {
+	switch f.WireType {
+	case protowire.VarintType:
+		return "varint"
+	case protowire.Fixed32Type:
+		return "fixed32"
+	case protowire.Fixed64Type:
+		return "fixed64"
+	case protowire.BytesType:
+		return "bytes"
+	case protowire.StartGroupType, protowire.EndGroupType:
+		return "group"
+	}
+	return fmt.Sprintf("wire type %d", f.WireType)
+}
+
+// FindUnknownFields returns the distinct unknown fields in m and its
+// submessages, ordered by message, field number and wire type. Run RecoverUnknownEnums
+// first so that unknown enum values of known fields aren't reported.
+func FindUnknownFields(m proto.Message) []UnknownField {
+	seen := make(map[UnknownField]struct{})
+	var walk func(m protoreflect.Message)
+	walk = func(m protoreflect.Message) {
+		b := []byte(m.GetUnknown())
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				break
+			}
+			vn := protowire.ConsumeFieldValue(num, typ, b[n:])
+			if vn < 0 {
+				break
+			}
+			b = b[n+vn:]
+			seen[UnknownField{
+				Message:  string(m.Descriptor().FullName()),
+				Number:   int32(num),
+				WireType: typ,
+			}] = struct{}{}
+		}
+		rangeSubmessages(m, walk)
+	}
+	walk(m.ProtoReflect())
+
+	var fields []UnknownField
+	for f := range seen {
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].Message != fields[j].Message {
+			return fields[i].Message < fields[j].Message
+		}
+		if fields[i].Number != fields[j].Number {
+			return fields[i].Number < fields[j].Number
+		}
+		return fields[i].WireType < fields[j].WireType
+	})
+	return fields
+}
+
+const (
+	UnknownValueKind_SHIP          = "ship"
+	UnknownValueKind_DURATION_TYPE = "duration type"
+	UnknownValueKind_EGG           = "egg"
+	UnknownValueKind_ARTIFACT      = "artifact"
+	UnknownValueKind_ARTIFACT_TIER = "artifact tier"
+	UnknownValueKind_RARITY        = "rarity"
+	UnknownValueKind_REWARD_TYPE   = "reward type"
+	UnknownValueKind_FIELD         = "field"
+)
+
+// UnknownValue is a value in a mission that the helpers of this package can't
+// handle, e.g. a ship added in a game update. Value is the raw enum value, or
+// for artifact tiers, the artifact id and level, and for fields, the
+// UnknownField.
+type UnknownValue struct {
+	Kind  string
+	Value string
+}
+
+func (v UnknownValue) String() string {
+	return v.Kind + " " + v.Value
+}
+
+// FindUnknownValues returns the distinct values in the mission not handled by
+// this build, in order of appearance. The response should have gone through
+// RecoverUnknownEnums, which the api package does when decoding.
+func FindUnknownValues(r *CompleteMissionResponse) []UnknownValue {
+	var values []UnknownValue
+	seen := make(map[UnknownValue]struct{})
+	add := func(kind string, value string) {
+		v := UnknownValue{Kind: kind, Value: value}
+		if _, exists := seen[v]; !exists {
+			values = append(values, v)
+			seen[v] = struct{}{}
+		}
+	}
+
+	info := r.GetInfo()
+	if ship := info.GetShip(); ship.Name() == unknownDisplay(int32(ship)) {
+		add(UnknownValueKind_SHIP, fmt.Sprint(int32(ship)))
+	}
+	if durationType := info.GetDurationType(); durationType.Display() == unknownDisplay(int32(durationType)) {
+		add(UnknownValueKind_DURATION_TYPE, fmt.Sprint(int32(durationType)))
+	}
+	for _, f := range info.GetFuel() {
+		if egg := f.GetEgg(); egg.Display() == unknownDisplay(int32(egg)) {
+			add(UnknownValueKind_EGG, fmt.Sprint(int32(egg)))
+		}
+	}
+	for _, a := range r.GetArtifacts() {
+		spec := a.GetSpec()
+		if spec == nil {
+			continue
+		}
+		name := spec.GetName()
+		if _, ok := _catalog[name]; !ok {
+			add(UnknownValueKind_ARTIFACT, fmt.Sprint(int32(name)))
+		} else if spec.catalogTier() == nil {
+			add(UnknownValueKind_ARTIFACT_TIER, fmt.Sprintf("%s %d", name.Id(), int32(spec.GetLevel())))
+		}
+		if rarity := spec.GetRarity(); rarity.Display() == unknownDisplay(int32(rarity)) {
+			add(UnknownValueKind_RARITY, fmt.Sprint(int32(rarity)))
+		}
+	}
+	for _, reward := range r.GetOtherRewards() {
+		if t := reward.GetRewardType(); t.Display() == unknownDisplay(int32(t)) {
+			add(UnknownValueKind_REWARD_TYPE, fmt.Sprint(int32(t)))
+		}
+	}
+	for _, f := range FindUnknownFields(r) {
+		add(UnknownValueKind_FIELD, f.String())
+	}
+	return values
+}
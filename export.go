@@ -9,6 +9,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -31,11 +33,27 @@ type mission struct {
 	Duration         time.Duration
 	DurationDays     float64
 	Capacity         uint32
+	// ExpectedCapacity and Quality are derived from the artifacts configuration
+	// and are zero if it's unavailable.
+	ExpectedCapacity uint32
+	Quality          float64
+	QualityBump      float64
+	Fuels            []*ei.MissionInfo_Fuel
+	OtherRewards     []*ei.Reward
 	Artifacts        []*ei.ArtifactSpec
 	ArtifactNames    []string
+	// Imported is true for missions imported from other sources than the game
+	// server, e.g. old spreadsheets. It's set by the caller of newMission.
+	Imported bool
+
+	// response is the mission as decoded, for consumers that need fields not
+	// carried over.
+	response *ei.CompleteMissionResponse
 }
 
-func newMission(r *ei.CompleteMissionResponse) *mission {
+// newMission creates a mission for export. config is optional and used for
+// annotating the mission with expected capacity and quality.
+func newMission(r *ei.CompleteMissionResponse, config *ei.ArtifactsConfigurationResponse) *mission {
 	info := r.GetInfo()
 	ship := info.GetShip()
 	durationType := info.GetDurationType()
@@ -49,6 +67,12 @@ func newMission(r *ei.CompleteMissionResponse) *mission {
 		artifacts = append(artifacts, a.Spec)
 		artifactNames = append(artifactNames, a.Spec.Display())
 	}
+	var expectedCapacity uint32
+	var quality float64
+	if params := config.GetDurationParameters(ship, durationType); params != nil {
+		expectedCapacity = params.CapacityAtLevel(info.GetLevel())
+		quality = params.QualityAtLevel(info.GetLevel())
+	}
 	return &mission{
 		Id:               info.GetIdentifier(),
 		Ship:             ship,
@@ -63,49 +87,276 @@ func newMission(r *ei.CompleteMissionResponse) *mission {
 		Duration:         duration,
 		DurationDays:     durationSeconds / 86400,
 		Capacity:         info.GetCapacity(),
+		ExpectedCapacity: expectedCapacity,
+		Quality:          quality,
+		QualityBump:      info.GetQualityBump(),
+		Fuels:            info.GetFuel(),
+		OtherRewards:     r.GetOtherRewards(),
 		Artifacts:        artifacts,
 		ArtifactNames:    artifactNames,
+		response:         r,
+	}
+}
+
+// FuelAmount returns the amount of the egg loaded as fuel for the mission.
+func (m *mission) FuelAmount(egg ei.Egg) float64 {
+	var amount float64
+	for _, f := range m.Fuels {
+		if f.GetEgg() == egg {
+			amount += f.GetAmount()
+		}
+	}
+	return amount
+}
+
+func fuelColumnName(l *localizer, egg ei.Egg) string {
+	return l.Header("fuel", "Fuel: %s", l.Egg(egg))
+}
+
+// RewardAmount returns the total amount of the kind of non-artifact reward
+// received from the mission.
+func (m *mission) RewardAmount(key rewardKey) float64 {
+	var amount float64
+	for _, r := range m.OtherRewards {
+		if r.GetRewardType() == key.Type && r.GetRewardSubType() == key.SubType {
+			amount += r.GetRewardAmount()
+		}
 	}
+	return amount
 }
 
-func exportMissionsToCsv(missions []*mission, path string) error {
+func rewardColumnName(l *localizer, key rewardKey) string {
+	return l.Header("reward", "Reward: %s", l.Reward(key))
+}
+
+// missionColumnNames returns the localized headers of the columns preceding
+// fuel, reward and artifact columns. durationKey and durationEnglish differ
+// between the CSV and XLSX exports.
+func missionColumnNames(l *localizer, durationKey string, durationEnglish string) []string {
+	return []string{
+		l.Header("id", "ID"),
+		l.Header("ship", "Ship"),
+		l.Header("type", "Type"),
+		l.Header("level", "Level"),
+		l.Header("launched_at", "Launched at"),
+		l.Header("returned_at", "Returned at"),
+		l.Header(durationKey, durationEnglish),
+		l.Header("capacity", "Capacity"),
+		l.Header("expected_capacity", "Expected capacity"),
+		l.Header("quality", "Quality"),
+	}
+}
+
+// missionIterator calls f with each mission to export in chronological order,
+// stopping at the first error, which is returned as is. Exporters call it more
+// than once, e.g. to lay out columns before writing rows, rather than holding
+// all missions in memory, so it must yield the same missions every time.
+type missionIterator func(f func(*mission) error) error
+
+// missionSlice iterates over missions already in memory.
+func missionSlice(missions []*mission) missionIterator {
+	return func(f func(*mission) error) error {
+		for _, m := range missions {
+			if err := f(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// missionConsumer passes over missions one or more times, e.g. an exporter.
+type missionConsumer func(missions missionIterator) error
+
+// shareMissionPasses runs consumers concurrently, each on its own goroutine,
+// over passes shared between them, so that missions are read and decoded once
+// per pass rather than once per pass of each consumer: a pass over source
+// starts once every consumer still running has asked for its next one, and
+// hands each mission to all of them in turn, on the calling goroutine.
+// Consumers making fewer passes than others simply drop out of later ones. It
+// returns the error of each consumer.
+func shareMissionPasses(source missionIterator, consumers ...missionConsumer) []error {
+	type passRequest struct {
+		f    func(*mission) error
+		done chan error
+	}
+	requests := make(chan *passRequest)
+	exited := make(chan struct{})
+	errs := make([]error, len(consumers))
+	for i, consume := range consumers {
+		go func(i int, consume missionConsumer) {
+			errs[i] = consume(func(f func(*mission) error) error {
+				r := &passRequest{f: f, done: make(chan error, 1)}
+				requests <- r
+				return <-r.done
+			})
+			exited <- struct{}{}
+		}(i, consume)
+	}
+
+	running := len(consumers)
+	var waiting []*passRequest
+	for running > 0 {
+		select {
+		case r := <-requests:
+			waiting = append(waiting, r)
+		case <-exited:
+			running--
+		}
+		if running == 0 || len(waiting) < running {
+			continue
+		}
+		passErrs := make([]error, len(waiting))
+		failed := 0
+		err := source(func(m *mission) error {
+			for i, r := range waiting {
+				if passErrs[i] != nil {
+					continue
+				}
+				if passErrs[i] = r.f(m); passErrs[i] != nil {
+					failed++
+				}
+			}
+			if failed == len(waiting) {
+				// Nobody is left to hand missions to.
+				return passErrs[0]
+			}
+			return nil
+		})
+		for i, r := range waiting {
+			if passErrs[i] == nil {
+				passErrs[i] = err
+			}
+			r.done <- passErrs[i]
+		}
+		waiting = nil
+	}
+	return errs
+}
+
+// missionLayout collects what determines the columns of a mission export.
+type missionLayout struct {
+	maxArtifactCount      int
+	maxArtifactNameLength int
+	eggs                  eggSet
+	rewards               rewardKeySet
+	// imported is whether any mission is imported, in which case exports have
+	// a source column telling them from server-verified missions.
+	imported bool
+}
+
+func newMissionLayout() *missionLayout {
+	return &missionLayout{
+		eggs:    make(eggSet),
+		rewards: make(rewardKeySet),
+	}
+}
+
+func (layout *missionLayout) add(m *mission, l *localizer) {
+	if count := len(m.ArtifactNames); count > layout.maxArtifactCount {
+		layout.maxArtifactCount = count
+	}
+	for _, a := range m.Artifacts {
+		if length := len([]rune(l.Artifact(a))); length > layout.maxArtifactNameLength {
+			layout.maxArtifactNameLength = length
+		}
+	}
+	layout.eggs.add(m.Fuels)
+	layout.rewards.add(m.OtherRewards)
+	if m.Imported {
+		layout.imported = true
+	}
+}
+
+func sourceColumnName(l *localizer) string {
+	return l.Header("source", "Source")
+}
+
+func (m *mission) Source(l *localizer) string {
+	if m.Imported {
+		return l.Header("source_imported", "Imported")
+	}
+	return ""
+}
+
+func exportMissionsToCsv(missions missionIterator, l *localizer, path string) error {
 	action := fmt.Sprintf("exporting missions to %s", path)
 	wrap := func(err error) error {
 		return errors.Wrap(err, "error "+action)
 	}
 
-	var maxArtifactCount int
-	for _, m := range missions {
-		count := len(m.ArtifactNames)
-		if count > maxArtifactCount {
-			maxArtifactCount = count
-		}
+	layout := newMissionLayout()
+	err := missions(func(m *mission) error {
+		layout.add(m, l)
+		return nil
+	})
+	if err != nil {
+		return wrap(err)
 	}
-	header := []string{"ID", "Ship", "Type", "Level", "Launched at", "Returned at", "Duration days", "Capacity"}
-	for i := 1; i <= maxArtifactCount; i++ {
-		header = append(header, fmt.Sprintf("Artifact %d", i))
+	eggs := layout.eggs.sorted()
+	rewards := layout.rewards.sorted()
+	header := missionColumnNames(l, "duration_days", "Duration days")
+	if layout.imported {
+		header = append(header, sourceColumnName(l))
 	}
-	records := [][]string{header}
-	for _, m := range missions {
-		record := []string{
-			m.Id,
-			m.ShipName,
-			m.DurationTypeName,
-			fmt.Sprint(m.Level),
-			m.LaunchedAtStr,
-			m.ReturnedAtStr,
-			fmt.Sprint(m.DurationDays),
-			fmt.Sprint(m.Capacity),
-		}
-		count := len(m.ArtifactNames)
-		for i := 0; i < maxArtifactCount; i++ {
-			if i < count {
-				record = append(record, m.ArtifactNames[i])
+	for _, egg := range eggs {
+		header = append(header, fuelColumnName(l, egg))
+	}
+	for _, key := range rewards {
+		header = append(header, rewardColumnName(l, key))
+	}
+	for i := 1; i <= layout.maxArtifactCount; i++ {
+		header = append(header, l.Header("artifact", "Artifact %d", i))
+	}
+	records := func(write func(record []string) error) error {
+		if err := write(header); err != nil {
+			return err
+		}
+		return missions(func(m *mission) error {
+			record := []string{
+				m.Id,
+				l.Ship(m.Ship),
+				l.DurationType(m.DurationType),
+				fmt.Sprint(m.Level),
+				m.LaunchedAtStr,
+				m.ReturnedAtStr,
+				fmt.Sprint(m.DurationDays),
+				fmt.Sprint(m.Capacity),
+			}
+			if m.ExpectedCapacity > 0 {
+				record = append(record, fmt.Sprint(m.ExpectedCapacity), strconv.FormatFloat(m.Quality, 'f', -1, 64))
 			} else {
-				record = append(record, "")
+				record = append(record, "", "")
 			}
-		}
-		records = append(records, record)
+			if layout.imported {
+				record = append(record, m.Source(l))
+			}
+			for _, egg := range eggs {
+				amount := m.FuelAmount(egg)
+				if amount > 0 {
+					record = append(record, strconv.FormatFloat(amount, 'f', -1, 64))
+				} else {
+					record = append(record, "")
+				}
+			}
+			for _, key := range rewards {
+				amount := m.RewardAmount(key)
+				if amount > 0 {
+					record = append(record, strconv.FormatFloat(amount, 'f', -1, 64))
+				} else {
+					record = append(record, "")
+				}
+			}
+			count := len(m.Artifacts)
+			for i := 0; i < layout.maxArtifactCount; i++ {
+				if i < count {
+					record = append(record, l.Artifact(m.Artifacts[i]))
+				} else {
+					record = append(record, "")
+				}
+			}
+			return write(record)
+		})
 	}
 
 	temp, err := writeCsvToTempfile(records, filepath.Dir(path), tempfilePattern(path))
@@ -119,48 +370,86 @@ func exportMissionsToCsv(missions []*mission, path string) error {
 	return nil
 }
 
-func writeCsvToTempfile(records [][]string, dir, pattern string) (temp string, err error) {
+// csvRecords calls write with each record to write, stopping at the first
+// error.
+type csvRecords func(write func(record []string) error) error
+
+// csvRecordSlice returns records already in memory.
+func csvRecordSlice(records [][]string) csvRecords {
+	return func(write func(record []string) error) error {
+		for _, record := range records {
+			if err := write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func writeCsvToTempfile(records csvRecords, dir, pattern string) (temp string, err error) {
 	f, err := os.CreateTemp(dir, pattern)
 	if err != nil {
 		return
 	}
 	_ = os.Chmod(f.Name(), 0644)
 	temp = f.Name()
-	defer func() { err = f.Close() }()
-	w := csv.NewWriter(f)
-	for _, record := range records {
-		err = w.Write(record)
-		if err != nil {
-			return
+	defer func() {
+		closeErr := f.Close()
+		if err == nil {
+			err = closeErr
 		}
-	}
-	w.Flush()
-	err = w.Error()
+	}()
+	w := csv.NewWriter(f)
+	err = records(w.Write)
 	if err != nil {
 		return
 	}
+	w.Flush()
+	err = w.Error()
 	return
 }
 
-func exportMissionsToXlsx(missions []*mission, path string) error {
+// reportInputs holds data beyond the mission list that goes into the summary
+// sheets of the .xlsx export.
+type reportInputs struct {
+	ActiveMissions []*activeMission
+	// Config is the artifacts configuration, which may be nil if unavailable.
+	Config *ei.ArtifactsConfigurationResponse
+}
+
+func exportMissionsToXlsx(missions missionIterator, inputs *reportInputs, l *localizer, path string) error {
 	action := fmt.Sprintf("exporting missions to %s", path)
 	wrap := func(err error) error {
 		return errors.Wrap(err, "error "+action)
 	}
 
-	var maxArtifactCount int
-	var maxArtifactNameLength int
-	for _, m := range missions {
-		count := len(m.ArtifactNames)
-		if count > maxArtifactCount {
-			maxArtifactCount = count
-		}
-		for _, name := range m.ArtifactNames {
-			if len(name) > maxArtifactNameLength {
-				maxArtifactNameLength = len(name)
-			}
-		}
+	// The first pass lays out columns and builds the summaries that only need
+	// one pass; the second writes mission rows and finishes the rest.
+	layout := newMissionLayout()
+	fuel := newFuelSummarizer()
+	rewardSummary := newRewardSummarizer()
+	yield := newYieldSummarizer()
+	ships := newShipProgressionSummarizer()
+	luck := newLuckSummarizer(inputs.Config)
+	droughts := newDroughtSummarizer()
+	err := missions(func(m *mission) error {
+		layout.add(m, l)
+		fuel.add(m)
+		rewardSummary.add(m)
+		yield.add(m)
+		ships.add(m)
+		luck.prepare(m)
+		droughts.prepare(m)
+		return nil
+	})
+	if err != nil {
+		return wrap(err)
 	}
+	eggs := layout.eggs.sorted()
+	rewards := layout.rewards.sorted()
+	imported := layout.imported
+	maxArtifactCount := layout.maxArtifactCount
+	maxArtifactNameLength := layout.maxArtifactNameLength
 
 	f := excelize.NewFile()
 	f.SetDefaultFont("Consolas")
@@ -173,57 +462,133 @@ func exportMissionsToXlsx(missions []*mission, path string) error {
 	if err != nil {
 		return wrap(err)
 	}
+	eggAmountStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: sptr("0.000E+00")})
+	if err != nil {
+		return wrap(err)
+	}
 
 	sw, err := f.NewStreamWriter("Sheet1")
 	if err != nil {
 		return wrap(err)
 	}
-	// Width of each column is set to max number of characters plus 5.
-	colWidths := []float64{56, 25, 13, 8, 24, 24, 13, 8}
+	var header []interface{}
+	for _, name := range missionColumnNames(l, "duration", "Duration") {
+		header = append(header, name)
+	}
+	if imported {
+		header = append(header, sourceColumnName(l))
+	}
+	for _, egg := range eggs {
+		header = append(header, fuelColumnName(l, egg))
+	}
+	for _, key := range rewards {
+		header = append(header, rewardColumnName(l, key))
+	}
+	for i := 1; i <= maxArtifactCount; i++ {
+		header = append(header, l.Header("artifact", "Artifact %d", i))
+	}
+
+	// Width of each column is set to max number of characters plus 5, at least
+	// enough for the header.
+	colWidths := []float64{56, 25, 13, 8, 24, 24, 13, 8, 22, 12}
+	if imported {
+		colWidths = append(colWidths, float64(len([]rune(l.Header("source_imported", "Imported")))+5))
+	}
+	for range eggs {
+		colWidths = append(colWidths, 0)
+	}
+	for range rewards {
+		colWidths = append(colWidths, 0)
+	}
 	for i := 1; i <= maxArtifactCount; i++ {
 		colWidths = append(colWidths, float64(maxArtifactNameLength+5))
 	}
+	colWidths = fitColWidths(colWidths, header)
 	for i, width := range colWidths {
 		if err := sw.SetColWidth(i+1, i+1, width); err != nil {
 			return wrap(err)
 		}
 	}
 
-	header := []interface{}{"ID", "Ship", "Type", "Level", "Launched at", "Returned at", "Duration", "Capacity"}
-	for i := 1; i <= maxArtifactCount; i++ {
-		header = append(header, fmt.Sprintf("Artifact %d", i))
-	}
 	if err = sw.SetRow("A1", header); err != nil {
 		return wrap(err)
 	}
 	rowId := 1
-	for _, m := range missions {
+	err = missions(func(m *mission) error {
+		luck.add(m)
+		droughts.add(m)
 		rowId++
 		row := []interface{}{
 			m.Id,
-			m.ShipName,
-			m.DurationTypeName,
+			l.Ship(m.Ship),
+			l.DurationType(m.DurationType),
 			m.Level,
 			&excelize.Cell{Value: m.LaunchedAt, StyleID: datetimeStyle},
 			&excelize.Cell{Value: m.ReturnedAt, StyleID: datetimeStyle},
 			&excelize.Cell{Value: m.DurationDays, StyleID: durationStyle},
 			m.Capacity,
 		}
-		for _, name := range m.ArtifactNames {
-			row = append(row, name)
+		if m.ExpectedCapacity > 0 {
+			row = append(row, m.ExpectedCapacity, m.Quality)
+		} else {
+			row = append(row, nil, nil)
+		}
+		if imported {
+			row = append(row, m.Source(l))
+		}
+		for _, egg := range eggs {
+			amount := m.FuelAmount(egg)
+			if amount > 0 {
+				row = append(row, &excelize.Cell{Value: amount, StyleID: eggAmountStyle})
+			} else {
+				row = append(row, nil)
+			}
+		}
+		for _, key := range rewards {
+			if amount := m.RewardAmount(key); amount > 0 {
+				row = append(row, amount)
+			} else {
+				row = append(row, nil)
+			}
+		}
+		for _, a := range m.Artifacts {
+			row = append(row, l.Artifact(a))
 		}
 		cell, err := excelize.CoordinatesToCellName(1, rowId)
 		if err != nil {
-			return wrap(err)
-		}
-		if err := sw.SetRow(cell, row); err != nil {
-			return wrap(err)
+			return err
 		}
+		return sw.SetRow(cell, row)
+	})
+	if err != nil {
+		return wrap(err)
 	}
 	if err := sw.Flush(); err != nil {
 		return wrap(err)
 	}
 
+	if err := writeFuelSheet(f, fuel.summary(), l, eggAmountStyle); err != nil {
+		return wrap(err)
+	}
+	for _, m := range inputs.ActiveMissions {
+		ships.addActive(m)
+	}
+	if err := writeShipsSheet(f, ships.summary(inputs.Config), l, datetimeStyle); err != nil {
+		return wrap(err)
+	}
+	if err := writeLuckSheet(f, luck.summary(), l, datetimeStyle); err != nil {
+		return wrap(err)
+	}
+	if err := writeRewardsSheet(f, rewardSummary.summary(), l); err != nil {
+		return wrap(err)
+	}
+	if err := writeYieldSheet(f, yield.summary(), l); err != nil {
+		return wrap(err)
+	}
+	if err := writeDroughtsSheet(f, droughts.summary(), l, datetimeStyle); err != nil {
+		return wrap(err)
+	}
+
 	temp, err := os.CreateTemp(filepath.Dir(path), tempfilePattern(path))
 	if err != nil {
 		return wrap(err)
@@ -246,6 +611,446 @@ func exportMissionsToXlsx(missions []*mission, path string) error {
 	return nil
 }
 
+func exportMissionsToIcs(playerId string, missions missionIterator, path string) error {
+	events := func(f func(*calendarEvent) error) error {
+		return missions(func(m *mission) error {
+			description := fmt.Sprintf("%s, %s, level %d, capacity %d\n%d items returned",
+				m.ShipName, m.DurationTypeName, m.Level, m.Capacity, len(m.Artifacts))
+			var notable []string
+			for _, a := range m.Artifacts {
+				if isNotableDrop(a) {
+					notable = append(notable, a.Display())
+				}
+			}
+			if len(notable) > 0 {
+				description += "\nNotable drops:\n" + strings.Join(notable, "\n")
+			}
+			if len(m.OtherRewards) > 0 {
+				var rewards []string
+				for _, r := range m.OtherRewards {
+					key := rewardKey{r.GetRewardType(), r.GetRewardSubType()}
+					rewards = append(rewards, fmt.Sprintf("%s ×%s", key.Display(), strconv.FormatFloat(r.GetRewardAmount(), 'f', -1, 64)))
+				}
+				description += "\nOther rewards:\n" + strings.Join(rewards, "\n")
+			}
+			return f(&calendarEvent{
+				Uid:         calendarUid(m.Id),
+				Start:       m.LaunchedAt,
+				End:         m.ReturnedAt,
+				Summary:     fmt.Sprintf("%s (%s)", m.ShipName, m.DurationTypeName),
+				Description: description,
+			})
+		})
+	}
+	return exportEventsToIcs(fmt.Sprintf("EggLedger missions (%s)", playerId), events, path)
+}
+
+// isNotableDrop reports whether an item is worth calling out in summaries:
+// anything of rare or higher rarity, or of the highest tier.
+func isNotableDrop(a *ei.ArtifactSpec) bool {
+	return a.GetRarity() > ei.ArtifactSpec_COMMON || a.TierNumber() >= 4
+}
+
+// writeFuelSheet adds a sheet summarizing fuel consumption per ship and
+// duration, per legendary drop, and per month.
+func writeFuelSheet(f *excelize.File, summary *fuelSummary, l *localizer, eggAmountStyle int) error {
+	amountCell := func(amount float64) interface{} {
+		if amount == 0 {
+			return nil
+		}
+		return &excelize.Cell{Value: amount, StyleID: eggAmountStyle}
+	}
+
+	var rows [][]interface{}
+	header := []interface{}{l.Header("ship", "Ship"), l.Header("type", "Type"), l.Header("missions", "Missions")}
+	for _, egg := range summary.Eggs {
+		header = append(header, l.Egg(egg))
+	}
+	rows = append(rows, []interface{}{l.Label("fuel_by_ship", "Fuel by ship")}, header)
+	for _, s := range summary.ByShip {
+		row := []interface{}{l.Ship(s.Ship), l.DurationType(s.DurationType), s.Missions}
+		for _, egg := range summary.Eggs {
+			row = append(row, amountCell(s.Fuel[egg]))
+		}
+		rows = append(rows, row)
+	}
+
+	header = []interface{}{l.Header("ship", "Ship"), l.Header("type", "Type"), l.Header("legendaries", "Legendaries")}
+	for _, egg := range summary.Eggs {
+		header = append(header, l.Egg(egg))
+	}
+	rows = append(rows, nil, []interface{}{l.Label("fuel_per_legendary", "Fuel per legendary drop")}, header)
+	for _, s := range summary.ByShip {
+		row := []interface{}{l.Ship(s.Ship), l.DurationType(s.DurationType), s.LegendaryDrops}
+		for _, egg := range summary.Eggs {
+			row = append(row, amountCell(s.FuelPerLegendary(egg)))
+		}
+		rows = append(rows, row)
+	}
+
+	header = []interface{}{l.Header("month", "Month"), "", l.Header("missions", "Missions")}
+	for _, egg := range summary.Eggs {
+		header = append(header, l.Egg(egg))
+	}
+	rows = append(rows, nil, []interface{}{l.Label("fuel_by_month", "Fuel by month")}, header)
+	for _, s := range summary.ByMonth {
+		row := []interface{}{s.Month.Format("2006-01"), nil, s.Missions}
+		for _, egg := range summary.Eggs {
+			row = append(row, amountCell(s.Fuel[egg]))
+		}
+		rows = append(rows, row)
+	}
+
+	colWidths := []float64{25, 13, 16}
+	for range summary.Eggs {
+		colWidths = append(colWidths, 18)
+	}
+	return writeXlsxSheet(f, l.Sheet("fuel", "Fuel"), colWidths, rows)
+}
+
+// writeRewardsSheet adds a sheet totaling non-artifact rewards, overall and per
+// month.
+func writeRewardsSheet(f *excelize.File, summary *rewardSummary, l *localizer) error {
+	amountCell := func(amount float64) interface{} {
+		if amount == 0 {
+			return nil
+		}
+		return amount
+	}
+
+	header := []interface{}{"", l.Header("missions", "Missions")}
+	for _, key := range summary.Keys {
+		header = append(header, l.Reward(key))
+	}
+	missions := 0
+	for _, m := range summary.ByMonth {
+		missions += m.Missions
+	}
+	total := []interface{}{l.Label("total", "Total"), missions}
+	for _, key := range summary.Keys {
+		total = append(total, amountCell(summary.Totals[key]))
+	}
+
+	var rows [][]interface{}
+	rows = append(rows, []interface{}{l.Label("rewards_other_than_items", "Rewards other than items")}, header, total, nil)
+	header = append([]interface{}{l.Header("month", "Month")}, header[1:]...)
+	rows = append(rows, []interface{}{l.Label("rewards_by_month", "Rewards by month")}, header)
+	for _, m := range summary.ByMonth {
+		row := []interface{}{m.Month.Format("2006-01"), m.Missions}
+		for _, key := range summary.Keys {
+			row = append(row, amountCell(m.Rewards[key]))
+		}
+		rows = append(rows, row)
+	}
+
+	return writeXlsxSheet(f, l.Sheet("rewards", "Rewards"), []float64{13, 10}, rows)
+}
+
+// writeYieldSheet adds a sheet converting stone fragments and ingredients into
+// crafted equivalents per ship and duration.
+func writeYieldSheet(f *excelize.File, r *yieldReport, l *localizer) error {
+	countCell := func(count int) interface{} {
+		if count == 0 {
+			return nil
+		}
+		return count
+	}
+
+	var rows [][]interface{}
+	rows = append(rows,
+		[]interface{}{l.Label("best_ship_per_item", "Best ship per item")},
+		[]interface{}{
+			l.Header("item", "Item"), l.Header("unit", "Unit"), l.Header("ship", "Ship"), l.Header("type", "Type"),
+			l.Header("per_mission", "Per mission"), l.Header("per_day", "Per day"),
+		})
+	for _, family := range r.Families {
+		best := r.Best(family)
+		rows = append(rows, []interface{}{
+			l.Family(family),
+			l.ArtifactName(yieldUnit(family)),
+			l.Ship(best.Ship),
+			l.DurationType(best.DurationType),
+			best.PerMission(),
+			best.PerDay(),
+		})
+	}
+
+	header := []interface{}{
+		l.Header("item", "Item"), l.Header("unit", "Unit"), l.Header("ship", "Ship"), l.Header("type", "Type"),
+		l.Header("missions", "Missions"),
+	}
+	for tier := 1; tier <= 4; tier++ {
+		header = append(header, l.Header("tier", "T%d", tier))
+	}
+	header = append(header,
+		l.Header("equivalent", "Equivalent"), l.Header("per_mission", "Per mission"), l.Header("per_day", "Per day"))
+	rows = append(rows, nil, []interface{}{l.Label("yield_by_ship", "Yield by ship")}, header)
+	for _, y := range r.Yields {
+		rows = append(rows, []interface{}{
+			l.Family(y.Family),
+			l.ArtifactName(yieldUnit(y.Family)),
+			l.Ship(y.Ship),
+			l.DurationType(y.DurationType),
+			y.Missions,
+			countCell(y.Counts[1]),
+			countCell(y.Counts[2]),
+			countCell(y.Counts[3]),
+			countCell(y.Counts[4]),
+			y.Equivalent,
+			y.PerMission(),
+			y.PerDay(),
+		})
+	}
+
+	colWidths := []float64{25, 28, 25, 13, 13, 8, 8, 8, 8, 13, 13, 13}
+	return writeXlsxSheet(f, l.Sheet("yield", "Yield"), colWidths, rows)
+}
+
+// writeShipsSheet adds a sheet tracking launches and star levels of each ship.
+// Launch points are estimated, see ei.MissionInfo_DurationType.LaunchPoints.
+func writeShipsSheet(f *excelize.File, p *shipProgression, l *localizer, datetimeStyle int) error {
+	var rows [][]interface{}
+	rows = append(rows,
+		[]interface{}{l.Label("current_levels", "Current levels")},
+		[]interface{}{
+			l.Header("ship", "Ship"), l.Header("launches", "Launches"), l.Header("level", "Level"),
+			l.Header("launches_at_level", "Launches at level"),
+			l.Header("launch_points_at_level", "Launch points at level (approx.)"),
+			l.Header("required_launch_points", "Required launch points"),
+			l.Header("remaining_launch_points", "Remaining launch points (approx.)"),
+			l.Header("projected_launches", "Projected launches (approx.)"),
+		})
+	for _, s := range p.Statuses {
+		row := []interface{}{l.Ship(s.Ship), s.Launches, s.Level, s.LaunchesAtLevel, s.LaunchPointsAtLevel}
+		switch {
+		case s.MaxLevel:
+			row = append(row, l.Label("max_level", "Max level"), nil, nil)
+		case s.RemainingLaunchPoints() < 0:
+			row = append(row, l.Label("unknown", "Unknown"), nil, nil)
+		default:
+			row = append(row, s.RequiredLaunchPoints, s.RemainingLaunchPoints(), s.RemainingLaunches())
+		}
+		rows = append(rows, row)
+	}
+
+	rows = append(rows,
+		nil,
+		[]interface{}{l.Label("level_history", "Level history")},
+		[]interface{}{
+			l.Header("ship", "Ship"), l.Header("launches", "Launches"), l.Header("level", "Level"),
+			l.Header("reached_at", "Reached at"),
+		})
+	for _, m := range p.Milestones {
+		rows = append(rows, []interface{}{
+			l.Ship(m.Ship),
+			m.Launches,
+			m.Level,
+			&excelize.Cell{Value: m.ReachedAt, StyleID: datetimeStyle},
+		})
+	}
+
+	header := []interface{}{l.Header("month", "Month"), l.Header("launches", "Launches")}
+	for _, ship := range p.Ships {
+		header = append(header, l.Ship(ship))
+	}
+	rows = append(rows, nil, []interface{}{l.Label("launches_by_month", "Launches by month")}, header)
+	for _, m := range p.ByMonth {
+		total := 0
+		for _, count := range m.Launches {
+			total += count
+		}
+		row := []interface{}{m.Month.Format("2006-01"), total}
+		for _, ship := range p.Ships {
+			if count := m.Launches[ship]; count > 0 {
+				row = append(row, count)
+			} else {
+				row = append(row, nil)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	colWidths := []float64{25, 13, 8, 24, 22, 22, 24, 18}
+	for i := len(colWidths); i < len(p.Ships)+2; i++ {
+		colWidths = append(colWidths, 25)
+	}
+	return writeXlsxSheet(f, l.Sheet("ships", "Ships"), colWidths, rows)
+}
+
+// writeLuckSheet adds a sheet comparing actual drops to expected drops.
+func writeLuckSheet(f *excelize.File, r *luckReport, l *localizer, datetimeStyle int) error {
+	perDrop := func(sum float64, drops int) interface{} {
+		if drops == 0 {
+			return nil
+		}
+		return sum / float64(drops)
+	}
+
+	var rows [][]interface{}
+	rows = append(rows,
+		[]interface{}{l.Label("average_drop_by_ship", "Average drop by ship")},
+		[]interface{}{
+			l.Header("ship", "Ship"), l.Header("type", "Type"), l.Header("level", "Level"),
+			l.Header("missions", "Missions"), l.Header("drops", "Drops"),
+			l.Header("expected_tier", "Expected tier"), l.Header("actual_tier", "Actual tier"),
+			l.Header("expected_rarity", "Expected rarity"), l.Header("actual_rarity", "Actual rarity"),
+			l.Header("tier_model", "Tier model"),
+		})
+	for _, g := range r.Groups {
+		model := l.Label("empirical", "Empirical")
+		if g.Modeled {
+			model = l.Label("configuration", "Configuration")
+		}
+		rows = append(rows, []interface{}{
+			l.Ship(g.Ship),
+			l.DurationType(g.DurationType),
+			g.Level,
+			g.Missions,
+			g.Drops,
+			perDrop(g.ExpectedTier, g.Drops),
+			perDrop(g.ActualTier, g.Drops),
+			perDrop(g.ExpectedRarity, g.Drops),
+			perDrop(g.ActualRarity, g.Drops),
+			model,
+		})
+	}
+
+	rows = append(rows,
+		nil,
+		[]interface{}{l.Label("unusual_missions", "Unusual missions")},
+		[]interface{}{
+			l.Header("id", "ID"), l.Header("ship", "Ship"), l.Header("type", "Type"), l.Header("level", "Level"),
+			l.Header("drops", "Drops"), l.Header("launched_at", "Launched at"),
+			l.Header("tier_luck", "Tier luck"), l.Header("rarity_luck", "Rarity luck"),
+			l.Header("score", "Score"), l.Header("verdict", "Verdict"),
+		})
+	for _, u := range r.Unusual {
+		verdict := l.Label("lucky", "Lucky")
+		if u.Score < 0 {
+			verdict = l.Label("unlucky", "Unlucky")
+		}
+		rows = append(rows, []interface{}{
+			u.Mission.Id,
+			l.Ship(u.Mission.Ship),
+			l.DurationType(u.Mission.DurationType),
+			u.Mission.Level,
+			u.Drops,
+			&excelize.Cell{Value: u.Mission.LaunchedAt, StyleID: datetimeStyle},
+			u.TierLuck(),
+			u.RarityLuck(),
+			u.Score,
+			verdict,
+		})
+	}
+
+	rows = append(rows,
+		nil,
+		[]interface{}{l.Label("luck_by_month", "Luck by month")},
+		[]interface{}{
+			l.Header("month", "Month"), l.Header("missions", "Missions"),
+			l.Header("luck", "Luck"), l.Header("cumulative_luck", "Cumulative luck"),
+		})
+	for _, m := range r.ByMonth {
+		rows = append(rows, []interface{}{m.Month.Format("2006-01"), m.Missions, m.Luck, m.Cumulative})
+	}
+
+	colWidths := []float64{56, 25, 13, 18, 8, 24, 13, 18, 18, 18}
+	return writeXlsxSheet(f, l.Sheet("luck", "Luck"), colWidths, rows)
+}
+
+// writeDroughtsSheet adds a sheet listing current and longest droughts and
+// streaks of notable drops. Current droughts in days extend to the return of
+// the last mission.
+func writeDroughtsSheet(f *excelize.File, stats []*droughtStats, l *localizer, datetimeStyle int) error {
+	days := func(d time.Duration) float64 {
+		return d.Hours() / 24
+	}
+
+	var rows [][]interface{}
+	rows = append(rows, []interface{}{
+		l.Header("scope", "Missions"), l.Header("item", "Item"),
+		l.Header("launches", "Launches"), l.Header("drops", "Drops"),
+		l.Header("current_drought", "Current drought"), l.Header("current_drought_days", "Current drought (days)"),
+		l.Header("longest_drought", "Longest drought"), l.Header("longest_drought_days", "Longest drought (days)"),
+		l.Header("current_streak", "Current streak"), l.Header("longest_streak", "Longest streak"),
+		l.Header("last_dropped_at", "Last dropped at"),
+	})
+	for _, s := range stats {
+		var lastSeen interface{}
+		if !s.LastSeen.IsZero() {
+			lastSeen = &excelize.Cell{Value: s.LastSeen, StyleID: datetimeStyle}
+		}
+		rows = append(rows, []interface{}{
+			l.DroughtScope(s.Scope),
+			l.DropTarget(s.Target),
+			s.Missions,
+			s.Matches,
+			s.CurrentDrought,
+			days(s.CurrentDroughtDuration),
+			s.LongestDrought,
+			days(s.LongestDroughtDuration),
+			s.CurrentStreak,
+			s.LongestStreak,
+			lastSeen,
+		})
+	}
+
+	colWidths := []float64{30, 30, 10, 8, 16, 22, 16, 22, 15, 15, 24}
+	return writeXlsxSheet(f, l.Sheet("droughts", "Droughts"), colWidths, rows)
+}
+
+// writeXlsxSheet adds a new sheet with the given column widths and rows. A nil
+// row is left blank. Columns are widened to fit text in rows of more than one
+// cell; single-cell rows are section titles, which may overflow.
+func writeXlsxSheet(f *excelize.File, sheet string, colWidths []float64, rows [][]interface{}) error {
+	for _, row := range rows {
+		if len(row) > 1 {
+			colWidths = fitColWidths(colWidths, row)
+		}
+	}
+	f.NewSheet(sheet)
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	for i, width := range colWidths {
+		if err := sw.SetColWidth(i+1, i+1, width); err != nil {
+			return err
+		}
+	}
+	for i, row := range rows {
+		if row == nil {
+			continue
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, row); err != nil {
+			return err
+		}
+	}
+	return sw.Flush()
+}
+
+// fitColWidths widens columns so that the text cells of row fit, at the usual
+// width of number of characters plus 5, extending colWidths as needed.
+func fitColWidths(colWidths []float64, row []interface{}) []float64 {
+	for i, cell := range row {
+		text, ok := cell.(string)
+		if !ok {
+			continue
+		}
+		for len(colWidths) <= i {
+			colWidths = append(colWidths, 0)
+		}
+		if width := float64(len([]rune(text)) + 5); width > colWidths[i] {
+			colWidths[i] = width
+		}
+	}
+	return colWidths
+}
+
 // findLastMatchingFile returns the path of the alphabetically last file in
 // directory matching the regexp pattern. Empty string is returned if there's no
 // file matching the pattern.
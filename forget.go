@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/fanaticscripter/EggLedger/db"
+)
+
+type forgetResult struct {
+	*db.PlayerDeletion
+	// Archive is the path of the archive kept before deleting, if any.
+	Archive string
+	// RemovedFiles are the exports removed.
+	RemovedFiles []string
+}
+
+// forgetAccount removes every trace of an account: its stored data, its exports
+// and its entry in known accounts. If archivePath isn't empty, an archive of its
+// missions and backups is written there first, which can be imported back with
+// import-archive; nothing is deleted if that fails.
+func forgetAccount(playerId string, archivePath string) (*forgetResult, error) {
+	action := fmt.Sprintf("forgetting account %s", playerId)
+	wrap := func(err error) error {
+		return errors.Wrap(err, "error "+action)
+	}
+	result := &forgetResult{}
+	if archivePath != "" {
+		if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+			return nil, wrap(err)
+		}
+		manifest, err := db.ExportArchive(archivePath, []string{playerId})
+		if err != nil {
+			return nil, wrap(err)
+		}
+		log.Infof("%s: archived %d missions and %d backups to %s",
+			playerId, len(manifest.Missions), len(manifest.Backups), archivePath)
+		if len(manifest.Skipped) > 0 {
+			// Unreadable entries can't be saved anyway, so they don't hold
+			// up forgetting the account.
+			log.Warnf("%s: %d unreadable entries left out of the archive", playerId, len(manifest.Skipped))
+		}
+		result.Archive = archivePath
+	}
+
+	deletion, err := db.DeletePlayerData(playerId)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	result.PlayerDeletion = deletion
+
+	exportsDir := filepath.Join(_rootDir, "exports")
+	id := regexp.QuoteMeta(playerId)
+	for _, e := range []struct {
+		dir     string
+		pattern string
+	}{
+		{filepath.Join(exportsDir, "missions"), `^` + id + `\.(\d{8}_\d{6}\.(xlsx|csv)|ics)$`},
+		{filepath.Join(exportsDir, "active"), `^` + id + `\.(csv|ics)$`},
+	} {
+		removed, err := removeMatchingFiles(e.dir, e.pattern)
+		result.RemovedFiles = append(result.RemovedFiles, removed...)
+		if err != nil {
+			return result, wrap(err)
+		}
+	}
+
+	_storage.RemoveKnownAccount(playerId)
+	return result, nil
+}
+
+// removeMatchingFiles removes files in directory whose names match pattern, and
+// returns the paths of those removed. A missing directory has nothing to
+// remove.
+func removeMatchingFiles(directory, pattern string) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var removed []string
+	for _, entry := range entries {
+		if entry.IsDir() || !re.MatchString(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(directory, entry.Name())
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
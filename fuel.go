@@ -0,0 +1,166 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// fuelTotals maps egg types to total amounts of fuel.
+type fuelTotals map[ei.Egg]float64
+
+func (t fuelTotals) add(fuels []*ei.MissionInfo_Fuel) {
+	for _, f := range fuels {
+		t[f.GetEgg()] += f.GetAmount()
+	}
+}
+
+// eggs returns the egg types in enum order.
+func (t fuelTotals) eggs() []ei.Egg {
+	s := make(eggSet)
+	for egg := range t {
+		s[egg] = struct{}{}
+	}
+	return s.sorted()
+}
+
+// shipDurationKey identifies a ship and mission duration combination, the
+// natural unit of comparison for mission statistics.
+type shipDurationKey struct {
+	Ship         ei.MissionInfo_Spaceship
+	DurationType ei.MissionInfo_DurationType
+}
+
+func (k shipDurationKey) less(other shipDurationKey) bool {
+	if k.Ship != other.Ship {
+		return k.Ship < other.Ship
+	}
+	return durationTypeOrder(k.DurationType) < durationTypeOrder(other.DurationType)
+}
+
+// durationTypeOrder orders duration types by length, with the tutorial
+// mission first.
+func durationTypeOrder(d ei.MissionInfo_DurationType) int {
+	if d == ei.MissionInfo_TUTORIAL {
+		return -1
+	}
+	return int(d)
+}
+
+type shipFuelSummary struct {
+	shipDurationKey
+	ShipName         string
+	DurationTypeName string
+	Missions         int
+	LegendaryDrops   int
+	Fuel             fuelTotals
+}
+
+// FuelPerLegendary returns the amount of the egg spent per legendary drop, or
+// 0 if there hasn't been any legendary drop.
+func (s *shipFuelSummary) FuelPerLegendary(egg ei.Egg) float64 {
+	if s.LegendaryDrops == 0 {
+		return 0
+	}
+	return s.Fuel[egg] / float64(s.LegendaryDrops)
+}
+
+type monthlyFuelSummary struct {
+	// Month is the first instant of the month in local time.
+	Month    time.Time
+	Missions int
+	Fuel     fuelTotals
+}
+
+type fuelSummary struct {
+	// Eggs lists all egg types ever used as fuel, in enum order.
+	Eggs    []ei.Egg
+	ByShip  []*shipFuelSummary
+	ByMonth []*monthlyFuelSummary
+}
+
+// fuelSummarizer builds a fuelSummary from missions added in chronological
+// order.
+type fuelSummarizer struct {
+	eggs   eggSet
+	byShip map[shipDurationKey]*shipFuelSummary
+	ships  []*shipFuelSummary
+	months []*monthlyFuelSummary
+}
+
+func newFuelSummarizer() *fuelSummarizer {
+	return &fuelSummarizer{
+		eggs:   make(eggSet),
+		byShip: make(map[shipDurationKey]*shipFuelSummary),
+	}
+}
+
+func (s *fuelSummarizer) add(m *mission) {
+	s.eggs.add(m.Fuels)
+	key := shipDurationKey{m.Ship, m.DurationType}
+	ship, ok := s.byShip[key]
+	if !ok {
+		ship = &shipFuelSummary{
+			shipDurationKey:  key,
+			ShipName:         m.ShipName,
+			DurationTypeName: m.DurationTypeName,
+			Fuel:             make(fuelTotals),
+		}
+		s.byShip[key] = ship
+		s.ships = append(s.ships, ship)
+	}
+	ship.Missions++
+	ship.Fuel.add(m.Fuels)
+	for _, a := range m.Artifacts {
+		if a.GetRarity() == ei.ArtifactSpec_LEGENDARY {
+			ship.LegendaryDrops++
+		}
+	}
+
+	// Missions are in chronological order, so a new month is always appended
+	// at the end.
+	year, month, _ := m.LaunchedAt.Date()
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, m.LaunchedAt.Location())
+	if len(s.months) == 0 || !s.months[len(s.months)-1].Month.Equal(monthStart) {
+		s.months = append(s.months, &monthlyFuelSummary{
+			Month: monthStart,
+			Fuel:  make(fuelTotals),
+		})
+	}
+	last := s.months[len(s.months)-1]
+	last.Missions++
+	last.Fuel.add(m.Fuels)
+}
+
+func (s *fuelSummarizer) summary() *fuelSummary {
+	sort.Slice(s.ships, func(i, j int) bool {
+		return s.ships[i].less(s.ships[j].shipDurationKey)
+	})
+	return &fuelSummary{
+		Eggs:    s.eggs.sorted(),
+		ByShip:  s.ships,
+		ByMonth: s.months,
+	}
+}
+
+// eggSet collects egg types used as fuel.
+type eggSet map[ei.Egg]struct{}
+
+func (s eggSet) add(fuels []*ei.MissionInfo_Fuel) {
+	for _, f := range fuels {
+		s[f.GetEgg()] = struct{}{}
+	}
+}
+
+// sorted returns the egg types in enum order.
+func (s eggSet) sorted() []ei.Egg {
+	var eggs []ei.Egg
+	for egg := range s {
+		eggs = append(eggs, egg)
+	}
+	sort.Slice(eggs, func(i, j int) bool {
+		return eggs[i] < eggs[j]
+	})
+	return eggs
+}
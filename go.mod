@@ -1,12 +1,13 @@
 module github.com/fanaticscripter/EggLedger
 
-go 1.17
+go 1.22
 
 require (
 	github.com/andybrewer/mack v0.0.0-20200226161639-15be3d47cc54
 	github.com/dustin/go-humanize v1.0.0
 	github.com/golang-migrate/migrate/v4 v4.15.1
 	github.com/hashicorp/go-version v1.3.0
+	github.com/klauspost/compress v1.18.0
 	github.com/mattn/go-sqlite3 v1.14.9
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.8.1
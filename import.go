@@ -0,0 +1,566 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xuri/excelize/v2"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fanaticscripter/EggLedger/db"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// Missions can be imported from the .csv and .xlsx exports of EggLedger, in any
+// export language, and from JSON files in the following format, for histories
+// kept with other tools:
+//
+//   {
+//     "missions": [
+//       {
+//         "id": "optional, generated from ship and launch time if missing",
+//         "ship": "HENERPRISE",
+//         "duration_type": "EPIC",
+//         "level": 2,
+//         "launched_at": "2021-11-01T12:00:00Z",
+//         "duration_seconds": 172800,
+//         "capacity": 40,
+//         "fuel": [{"egg": "ANTIMATTER", "amount": 5e15}],
+//         "rewards": [{"type": "GOLD", "sub_type": "", "amount": 100}],
+//         "artifacts": ["Jeweled gusset (T4), Legendary"]
+//       }
+//     ]
+//   }
+//
+// Ships, duration types, eggs and reward types are enum names or English names
+// (e.g. Henerprise, Extended), and artifacts are named as in English exports.
+//
+// Imported missions are stored flagged as imported, and are replaced by the
+// server version if the server still has them.
+
+type importedMissionsFile struct {
+	Missions []*importedMission `json:"missions"`
+}
+
+type importedMission struct {
+	Id              string            `json:"id"`
+	Ship            string            `json:"ship"`
+	DurationType    string            `json:"duration_type"`
+	Level           uint32            `json:"level"`
+	LaunchedAt      string            `json:"launched_at"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	Capacity        uint32            `json:"capacity"`
+	Fuel            []*importedFuel   `json:"fuel"`
+	Rewards         []*importedReward `json:"rewards"`
+	Artifacts       []string          `json:"artifacts"`
+}
+
+type importedFuel struct {
+	Egg    string  `json:"egg"`
+	Amount float64 `json:"amount"`
+}
+
+type importedReward struct {
+	Type    string  `json:"type"`
+	SubType string  `json:"sub_type"`
+	Amount  float64 `json:"amount"`
+}
+
+// importMissions reads missions from the file, whose format is determined by
+// its extension, and stores them as imported missions of the player. Missions
+// already stored are skipped.
+func importMissions(playerId string, path string) (inserted int, skipped int, err error) {
+	action := fmt.Sprintf("import missions from %s", path)
+	wrap := func(err error) error {
+		return errors.Wrap(err, action)
+	}
+	var missions []*ei.CompleteMissionResponse
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		missions, err = readMissionsCsv(path)
+	case ".xlsx":
+		missions, err = readMissionsXlsx(path)
+	case ".json":
+		missions, err = readMissionsJson(path)
+	default:
+		err = errors.New("unsupported file type, expected .csv, .xlsx or .json")
+	}
+	if err != nil {
+		return 0, 0, wrap(err)
+	}
+	return db.InsertImportedMissions(playerId, missions)
+}
+
+func readMissionsCsv(path string) ([]*ei.CompleteMissionResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return readMissionTable(rows)
+}
+
+func readMissionsXlsx(path string) ([]*ei.CompleteMissionResponse, error) {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Missions are on the first sheet. Raw values are read so that dates and
+	// durations are numbers regardless of cell formats.
+	rows, err := f.GetRows(f.GetSheetName(0), excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	return readMissionTable(rows)
+}
+
+func readMissionsJson(path string) ([]*ei.CompleteMissionResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file importedMissionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	index := newDisplayIndex(getLocalizer(_defaultLanguage))
+	var missions []*ei.CompleteMissionResponse
+	for i, m := range file.Missions {
+		r, err := m.toCompleteMission(index)
+		if err != nil {
+			return nil, errors.Wrapf(err, "mission %d", i+1)
+		}
+		missions = append(missions, r)
+	}
+	return missions, nil
+}
+
+func (m *importedMission) toCompleteMission(index *displayIndex) (*ei.CompleteMissionResponse, error) {
+	ship, err := index.parseShip(m.Ship)
+	if err != nil {
+		return nil, err
+	}
+	durationType, err := index.parseDurationType(m.DurationType)
+	if err != nil {
+		return nil, err
+	}
+	launchedAt, err := time.Parse(time.RFC3339, m.LaunchedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "launched_at")
+	}
+	if m.DurationSeconds <= 0 {
+		return nil, errors.New("duration_seconds must be positive")
+	}
+	r := newImportedMission(m.Id, ship, durationType, launchedAt, m.DurationSeconds)
+	r.Info.Level = proto.Uint32(m.Level)
+	r.Info.Capacity = proto.Uint32(m.Capacity)
+	for _, f := range m.Fuel {
+		egg, err := parseEgg(f.Egg)
+		if err != nil {
+			return nil, err
+		}
+		r.Info.Fuel = append(r.Info.Fuel, &ei.MissionInfo_Fuel{Egg: egg.Enum(), Amount: proto.Float64(f.Amount)})
+	}
+	for _, reward := range m.Rewards {
+		t, err := parseRewardType(reward.Type)
+		if err != nil {
+			return nil, err
+		}
+		r.OtherRewards = append(r.OtherRewards, &ei.Reward{
+			RewardType:    t.Enum(),
+			RewardSubType: proto.String(reward.SubType),
+			RewardAmount:  proto.Float64(reward.Amount),
+		})
+	}
+	for _, name := range m.Artifacts {
+		spec, err := index.parseArtifact(name)
+		if err != nil {
+			return nil, err
+		}
+		r.Artifacts = append(r.Artifacts, &ei.CompleteMissionResponse_SecureArtifactSpec{Spec: spec})
+	}
+	return r, nil
+}
+
+// newImportedMission returns a mission with the basic info filled in. The id
+// is generated from the ship and launch time if empty.
+func newImportedMission(id string, ship ei.MissionInfo_Spaceship, durationType ei.MissionInfo_DurationType,
+	launchedAt time.Time, durationSeconds float64) *ei.CompleteMissionResponse {
+	if id == "" {
+		id = fmt.Sprintf("imported-%s-%d", ship, launchedAt.Unix())
+	}
+	return &ei.CompleteMissionResponse{
+		Success: proto.Bool(true),
+		Info: &ei.MissionInfo{
+			Identifier:       proto.String(id),
+			Ship:             ship.Enum(),
+			DurationType:     durationType.Enum(),
+			Status:           ei.MissionInfo_ARCHIVED.Enum(),
+			DurationSeconds:  proto.Float64(durationSeconds),
+			StartTimeDerived: proto.Float64(timeToUnix(launchedAt)),
+		},
+	}
+}
+
+// displayIndex maps the localized names used in exports back to values, for
+// one language.
+type displayIndex struct {
+	l             *localizer
+	ships         map[string]ei.MissionInfo_Spaceship
+	durationTypes map[string]ei.MissionInfo_DurationType
+	artifacts     map[string]*ei.ArtifactSpec
+	rarities      map[string]ei.ArtifactSpec_Rarity
+}
+
+func newDisplayIndex(l *localizer) *displayIndex {
+	index := &displayIndex{
+		l:             l,
+		ships:         make(map[string]ei.MissionInfo_Spaceship),
+		durationTypes: make(map[string]ei.MissionInfo_DurationType),
+		artifacts:     make(map[string]*ei.ArtifactSpec),
+		rarities:      make(map[string]ei.ArtifactSpec_Rarity),
+	}
+	for value := range ei.MissionInfo_Spaceship_name {
+		ship := ei.MissionInfo_Spaceship(value)
+		index.ships[l.Ship(ship)] = ship
+	}
+	for value := range ei.MissionInfo_DurationType_name {
+		durationType := ei.MissionInfo_DurationType(value)
+		index.durationTypes[l.DurationType(durationType)] = durationType
+	}
+	for value := range ei.ArtifactSpec_Rarity_name {
+		rarity := ei.ArtifactSpec_Rarity(value)
+		index.rarities[l.Rarity(rarity)] = rarity
+	}
+	for _, name := range ei.KnownArtifactNames() {
+		// Stone fragments are named the same at every level; the lowest level
+		// wins.
+		for level := ei.ArtifactSpec_INFERIOR; level <= ei.ArtifactSpec_SUPERIOR; level++ {
+			for rarity := ei.ArtifactSpec_COMMON; rarity <= ei.ArtifactSpec_LEGENDARY; rarity++ {
+				spec := &ei.ArtifactSpec{Name: name.Enum(), Level: level.Enum(), Rarity: rarity.Enum()}
+				display := l.Artifact(spec)
+				if _, exists := index.artifacts[display]; !exists {
+					index.artifacts[display] = spec
+				}
+			}
+		}
+	}
+	return index
+}
+
+// parseUnknownDisplay parses the name exported for values unknown to the app,
+// e.g. Unknown (11).
+func parseUnknownDisplay(s string) (int32, bool) {
+	var value int32
+	if _, err := fmt.Sscanf(s, "Unknown (%d)", &value); err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+func (index *displayIndex) parseShip(s string) (ei.MissionInfo_Spaceship, error) {
+	if ship, ok := index.ships[s]; ok {
+		return ship, nil
+	}
+	if value, ok := parseUnknownDisplay(s); ok {
+		return ei.MissionInfo_Spaceship(value), nil
+	}
+	return parseShip(s)
+}
+
+func (index *displayIndex) parseDurationType(s string) (ei.MissionInfo_DurationType, error) {
+	if durationType, ok := index.durationTypes[s]; ok {
+		return durationType, nil
+	}
+	if value, ok := parseUnknownDisplay(s); ok {
+		return ei.MissionInfo_DurationType(value), nil
+	}
+	return parseDurationType(s)
+}
+
+// _exportedArtifactPattern matches item names in exports, e.g. "Jeweled gusset
+// (T4), Legendary".
+var _exportedArtifactPattern = regexp.MustCompile(`^(.+) \(T(\d+)\)(?:, (.+))?$`)
+
+func (index *displayIndex) parseArtifact(s string) (*ei.ArtifactSpec, error) {
+	s = strings.TrimSpace(s)
+	if spec, ok := index.artifacts[s]; ok {
+		return proto.Clone(spec).(*ei.ArtifactSpec), nil
+	}
+	// Items and rarities unknown to the app are exported with their raw
+	// numbers, e.g. "Greater 99 (T4)" or "Jeweled gusset (T4), Unknown (5)".
+	match := _exportedArtifactPattern.FindStringSubmatch(s)
+	if match == nil {
+		return nil, errors.Errorf("unknown item %#v", s)
+	}
+	var spec *ei.ArtifactSpec
+	if known, ok := index.artifacts[fmt.Sprintf("%s (T%s)", match[1], match[2])]; ok {
+		spec = proto.Clone(known).(*ei.ArtifactSpec)
+	} else if spec = parseUnknownArtifactName(match[1]); spec == nil {
+		return nil, errors.Errorf("unknown item %#v", s)
+	}
+	if match[3] != "" {
+		rarity, ok := index.rarities[match[3]]
+		if !ok {
+			value, ok := parseUnknownDisplay(match[3])
+			if !ok {
+				return nil, errors.Errorf("unknown rarity %#v of item %#v", match[3], s)
+			}
+			rarity = ei.ArtifactSpec_Rarity(value)
+		}
+		spec.Rarity = rarity.Enum()
+	}
+	return spec, nil
+}
+
+// parseUnknownArtifactName parses the name exported for an item unknown to the
+// app, which is its level followed by its raw number, e.g. "Greater 99", and
+// returns a common item, or nil if s isn't such a name.
+func parseUnknownArtifactName(s string) *ei.ArtifactSpec {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil
+	}
+	var level ei.ArtifactSpec_Level
+	if value, ok := ei.ArtifactSpec_Level_value[strings.ToUpper(fields[0])]; ok {
+		level = ei.ArtifactSpec_Level(value)
+	} else if value, err := strconv.ParseInt(fields[0], 10, 32); err == nil {
+		level = ei.ArtifactSpec_Level(value)
+	} else {
+		return nil
+	}
+	value, err := strconv.ParseInt(fields[1], 10, 32)
+	if err != nil {
+		return nil
+	}
+	return &ei.ArtifactSpec{
+		Name:   ei.ArtifactSpec_Name(value).Enum(),
+		Level:  level.Enum(),
+		Rarity: ei.ArtifactSpec_COMMON.Enum(),
+	}
+}
+
+// parseRewardColumn parses a reward column header, e.g. "Reward: Piggy Golden
+// Egg (x2)".
+func (index *displayIndex) parseRewardColumn(header string) (rewardKey, bool) {
+	format := index.l.message("header.reward", "Reward: %s")
+	parts := strings.SplitN(format, "%s", 2)
+	if len(parts) != 2 || !strings.HasPrefix(header, parts[0]) || !strings.HasSuffix(header, parts[1]) ||
+		len(header) < len(parts[0])+len(parts[1]) {
+		return rewardKey{}, false
+	}
+	name := header[len(parts[0]) : len(header)-len(parts[1])]
+	for value := range ei.RewardType_name {
+		t := ei.RewardType(value)
+		typeName := index.l.Reward(rewardKey{Type: t})
+		if name == typeName {
+			return rewardKey{Type: t}, true
+		}
+		if strings.HasPrefix(name, typeName+" (") && strings.HasSuffix(name, ")") {
+			return rewardKey{Type: t, SubType: name[len(typeName)+2 : len(name)-1]}, true
+		}
+	}
+	return rewardKey{}, false
+}
+
+// tableRow is a mission being read from a row of an export.
+type tableRow struct {
+	id           string
+	ship         *ei.MissionInfo_Spaceship
+	durationType *ei.MissionInfo_DurationType
+	level        uint32
+	capacity     uint32
+	launchedAt   time.Time
+	returnedAt   time.Time
+	durationDays float64
+	fuels        []*ei.MissionInfo_Fuel
+	rewards      []*ei.Reward
+	artifacts    []*ei.CompleteMissionResponse_SecureArtifactSpec
+}
+
+type cellParser func(row *tableRow, cell string) error
+
+// readMissionTable reads missions from the rows of a mission export, the first
+// row being the header. The export language is detected from the header.
+func readMissionTable(rows [][]string) ([]*ei.CompleteMissionResponse, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	header := rows[0]
+	var parsers []cellParser
+	var matched int
+	for _, language := range exportLanguages() {
+		p, n := matchColumns(header, newDisplayIndex(getLocalizer(language.Code)))
+		if n > matched {
+			parsers, matched = p, n
+		}
+	}
+	// ID, ship, type, level, launch time and duration at least.
+	if matched < 6 {
+		return nil, errors.New("unrecognized header, expected a mission export of EggLedger")
+	}
+
+	var missions []*ei.CompleteMissionResponse
+	for i, cells := range rows[1:] {
+		wrap := func(err error) error {
+			return errors.Wrapf(err, "row %d", i+2)
+		}
+		row := &tableRow{}
+		for j, cell := range cells {
+			cell = strings.TrimSpace(cell)
+			if j >= len(parsers) || parsers[j] == nil || cell == "" {
+				continue
+			}
+			if err := parsers[j](row, cell); err != nil {
+				return nil, wrap(errors.Wrapf(err, "column %#v", header[j]))
+			}
+		}
+		if row.ship == nil || row.durationType == nil || row.launchedAt.IsZero() {
+			return nil, wrap(errors.New("ship, type and launch time are required"))
+		}
+		durationSeconds := math.Round(row.durationDays * 86400)
+		if durationSeconds <= 0 && !row.returnedAt.IsZero() {
+			durationSeconds = row.returnedAt.Sub(row.launchedAt).Seconds()
+		}
+		if durationSeconds <= 0 {
+			return nil, wrap(errors.New("duration or return time is required"))
+		}
+		r := newImportedMission(row.id, *row.ship, *row.durationType, row.launchedAt, durationSeconds)
+		r.Info.Level = proto.Uint32(row.level)
+		r.Info.Capacity = proto.Uint32(row.capacity)
+		r.Info.Fuel = row.fuels
+		r.OtherRewards = row.rewards
+		r.Artifacts = row.artifacts
+		missions = append(missions, r)
+	}
+	return missions, nil
+}
+
+// matchColumns returns parsers for the columns of an export in the language of
+// the index, nil for unrecognized columns, and the number of recognized
+// columns.
+func matchColumns(header []string, index *displayIndex) ([]cellParser, int) {
+	l := index.l
+	ignore := func(row *tableRow, cell string) error {
+		return nil
+	}
+	parseDays := func(row *tableRow, cell string) error {
+		days, err := strconv.ParseFloat(cell, 64)
+		row.durationDays = days
+		return err
+	}
+	known := map[string]cellParser{
+		l.Header("id", "ID"): func(row *tableRow, cell string) error {
+			row.id = cell
+			return nil
+		},
+		l.Header("ship", "Ship"): func(row *tableRow, cell string) error {
+			ship, err := index.parseShip(cell)
+			row.ship = &ship
+			return err
+		},
+		l.Header("type", "Type"): func(row *tableRow, cell string) error {
+			durationType, err := index.parseDurationType(cell)
+			row.durationType = &durationType
+			return err
+		},
+		l.Header("level", "Level"): func(row *tableRow, cell string) error {
+			level, err := strconv.ParseUint(cell, 10, 32)
+			row.level = uint32(level)
+			return err
+		},
+		l.Header("launched_at", "Launched at"): func(row *tableRow, cell string) (err error) {
+			row.launchedAt, err = parseExportedTime(cell)
+			return
+		},
+		l.Header("returned_at", "Returned at"): func(row *tableRow, cell string) (err error) {
+			row.returnedAt, err = parseExportedTime(cell)
+			return
+		},
+		l.Header("duration_days", "Duration days"): parseDays,
+		l.Header("duration", "Duration"):           parseDays,
+		l.Header("capacity", "Capacity"): func(row *tableRow, cell string) error {
+			capacity, err := strconv.ParseUint(cell, 10, 32)
+			row.capacity = uint32(capacity)
+			return err
+		},
+		// Derived from the artifacts configuration, not part of the mission.
+		l.Header("expected_capacity", "Expected capacity"): ignore,
+		l.Header("quality", "Quality"):                     ignore,
+		l.Header("source", "Source"):                       ignore,
+	}
+	for value := range ei.Egg_name {
+		egg := ei.Egg(value)
+		known[fuelColumnName(l, egg)] = func(row *tableRow, cell string) error {
+			amount, err := strconv.ParseFloat(cell, 64)
+			row.fuels = append(row.fuels, &ei.MissionInfo_Fuel{Egg: egg.Enum(), Amount: proto.Float64(amount)})
+			return err
+		}
+	}
+	for i := 1; i <= len(header); i++ {
+		known[l.Header("artifact", "Artifact %d", i)] = func(row *tableRow, cell string) error {
+			spec, err := index.parseArtifact(cell)
+			row.artifacts = append(row.artifacts, &ei.CompleteMissionResponse_SecureArtifactSpec{Spec: spec})
+			return err
+		}
+	}
+
+	parsers := make([]cellParser, len(header))
+	var matched int
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if p, ok := known[name]; ok {
+			parsers[i] = p
+			matched++
+		} else if key, ok := index.parseRewardColumn(name); ok {
+			parsers[i] = func(row *tableRow, cell string) error {
+				amount, err := strconv.ParseFloat(cell, 64)
+				row.rewards = append(row.rewards, &ei.Reward{
+					RewardType:    key.Type.Enum(),
+					RewardSubType: proto.String(key.SubType),
+					RewardAmount:  proto.Float64(amount),
+				})
+				return err
+			}
+			matched++
+		}
+	}
+	return parsers, matched
+}
+
+// parseExportedTime parses times as exported to .csv (RFC 3339) and .xlsx
+// (Excel serial numbers of local times).
+func parseExportedTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local); err == nil {
+		return t, nil
+	}
+	serial, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, errors.Errorf("unrecognized time %#v", s)
+	}
+	t, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t = t.Round(time.Second)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), nil
+}
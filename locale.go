@@ -0,0 +1,229 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// Exports can be localized with message catalogs, one JSON file per language,
+// keyed by enum names:
+//
+//   ship.<MissionInfo_Spaceship>, e.g. ship.HENERPRISE
+//   duration.<MissionInfo_DurationType>, e.g. duration.EPIC
+//   rarity.<ArtifactSpec_Rarity>, e.g. rarity.LEGENDARY
+//   item.<ArtifactSpec_Name>, e.g. item.ORNATE_GUSSET, for the whole family
+//   item.<ArtifactSpec_Name>.<ArtifactSpec_Level>, e.g. item.ORNATE_GUSSET.GREATER
+//   egg.<Egg>, e.g. egg.ANTIMATTER
+//   reward.<RewardType>, e.g. reward.GOLD
+//   header.<column>, e.g. header.launched_at
+//   label.<text>, e.g. label.fuel_by_ship, for titles and values in summary sheets
+//   sheet.<sheet>, e.g. sheet.fuel
+//
+// Missing messages fall back to English, so a catalog may be partial. English
+// itself needs no catalog. Besides the built-in catalogs, users can add or
+// replace catalogs in the locales folder next to the app.
+
+const _defaultLanguage = "en"
+
+//go:embed locales/*.json
+var _localesFS embed.FS
+
+type localeFile struct {
+	// Name is the name of the language in that language, e.g. Deutsch.
+	Name     string            `json:"name"`
+	Messages map[string]string `json:"messages"`
+}
+
+type exportLanguage struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type localizer struct {
+	code     string
+	messages map[string]string
+}
+
+var _localizers = map[string]*localizer{
+	_defaultLanguage: {code: _defaultLanguage},
+}
+var _languages = []*exportLanguage{{Code: _defaultLanguage, Name: "English"}}
+
+// localesInit loads built-in message catalogs, then those in userDir, which
+// take precedence.
+func localesInit(userDir string) {
+	entries, err := _localesFS.ReadDir("locales")
+	if err != nil {
+		log.Errorf("error loading built-in locales: %s", err)
+	}
+	for _, entry := range entries {
+		data, err := _localesFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			log.Errorf("error loading built-in locale %s: %s", entry.Name(), err)
+			continue
+		}
+		registerLocale(entry.Name(), data)
+	}
+
+	userEntries, err := os.ReadDir(userDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("error loading locales from %s: %s", userDir, err)
+		}
+		return
+	}
+	for _, entry := range userEntries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(userDir, entry.Name()))
+		if err != nil {
+			log.Errorf("error loading locale %s: %s", entry.Name(), err)
+			continue
+		}
+		registerLocale(entry.Name(), data)
+	}
+}
+
+func registerLocale(filename string, data []byte) {
+	code := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if code == _defaultLanguage {
+		log.Warnf("ignoring locale %s: English is built in", filename)
+		return
+	}
+	var f localeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		log.Errorf("error parsing locale %s: %s", filename, err)
+		return
+	}
+	if f.Name == "" {
+		f.Name = code
+	}
+	if _, exists := _localizers[code]; !exists {
+		_languages = append(_languages, &exportLanguage{Code: code})
+	}
+	_localizers[code] = &localizer{code: code, messages: f.Messages}
+	for _, l := range _languages {
+		if l.Code == code {
+			l.Name = f.Name
+		}
+	}
+	sort.SliceStable(_languages[1:], func(i, j int) bool {
+		return _languages[i+1].Code < _languages[j+1].Code
+	})
+}
+
+func exportLanguages() []*exportLanguage {
+	return _languages
+}
+
+// getLocalizer returns the localizer for the language, falling back to English
+// if the language is unknown.
+func getLocalizer(code string) *localizer {
+	if l, ok := _localizers[code]; ok {
+		return l
+	}
+	return _localizers[_defaultLanguage]
+}
+
+// message returns the message for key, or english if there is none.
+func (l *localizer) message(key string, english string) string {
+	if s, ok := l.messages[key]; ok && s != "" {
+		return s
+	}
+	return english
+}
+
+func (l *localizer) Ship(s ei.MissionInfo_Spaceship) string {
+	return l.message("ship."+s.String(), s.Name())
+}
+
+func (l *localizer) DurationType(d ei.MissionInfo_DurationType) string {
+	return l.message("duration."+d.String(), d.Display())
+}
+
+func (l *localizer) Rarity(r ei.ArtifactSpec_Rarity) string {
+	return l.message("rarity."+r.String(), r.Display())
+}
+
+func (l *localizer) Egg(e ei.Egg) string {
+	return l.message("egg."+e.String(), e.Display())
+}
+
+func (l *localizer) Reward(k rewardKey) string {
+	name := l.message("reward."+k.Type.String(), k.Type.Display())
+	if k.SubType == "" {
+		return name
+	}
+	return name + " (" + k.SubType + ")"
+}
+
+// Family returns the localized name of an artifact family.
+func (l *localizer) Family(a ei.ArtifactSpec_Name) string {
+	return l.message("item."+a.Id(), a.CasedName())
+}
+
+// ArtifactName returns the localized name of an item, without tier or rarity.
+func (l *localizer) ArtifactName(a *ei.ArtifactSpec) string {
+	return l.message(fmt.Sprintf("item.%s.%s", a.GetName().Id(), a.GetLevel()), a.CasedName())
+}
+
+// Artifact is the localized equivalent of ArtifactSpec.Display.
+func (l *localizer) Artifact(a *ei.ArtifactSpec) string {
+	s := fmt.Sprintf("%s (T%d)", l.ArtifactName(a), a.TierNumber())
+	if a.GetRarity() > 0 {
+		s += fmt.Sprintf(", %s", l.Rarity(a.GetRarity()))
+	}
+	return s
+}
+
+// Header returns the localized column header. Headers with parameters take
+// them through format verbs, e.g. "Artifact %d".
+func (l *localizer) Header(key string, english string, args ...interface{}) string {
+	return l.format("header."+key, english, args...)
+}
+
+// Label returns a localized section title or cell value of a summary sheet,
+// with parameters like Header.
+func (l *localizer) Label(key string, english string, args ...interface{}) string {
+	return l.format("label."+key, english, args...)
+}
+
+// Sheet returns the localized name of a summary sheet.
+func (l *localizer) Sheet(key string, english string) string {
+	return l.message("sheet."+key, english)
+}
+
+// DroughtScope is the localized equivalent of droughtScope.Display.
+func (l *localizer) DroughtScope(s droughtScope) string {
+	if s.All {
+		return l.Label("all_missions", "All missions")
+	}
+	return fmt.Sprintf("%s (%s)", l.Ship(s.Ship), l.DurationType(s.DurationType))
+}
+
+// DropTarget is the localized equivalent of dropTarget.Display.
+func (l *localizer) DropTarget(t dropTarget) string {
+	if t.Family == nil {
+		return l.Label("any_item", "%s (any item)", l.Rarity(t.Rarity))
+	}
+	return fmt.Sprintf("%s %s", l.Rarity(t.Rarity), l.Family(*t.Family))
+}
+
+func (l *localizer) format(key string, english string, args ...interface{}) string {
+	format := l.message(key, english)
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/fanaticscripter/EggLedger/db"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+type artifactFamily struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// artifactFamilies lists all artifact families, grouped by type.
+func artifactFamilies() []*artifactFamily {
+	var names []ei.ArtifactSpec_Name
+	for _, name := range ei.KnownArtifactNames() {
+		if name.Family() == name {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ti, tj := names[i].ArtifactType(), names[j].ArtifactType()
+		if ti != tj {
+			return ti < tj
+		}
+		return names[i].CasedName() < names[j].CasedName()
+	})
+	var families []*artifactFamily
+	for _, name := range names {
+		families = append(families, &artifactFamily{
+			Id:   name.Id(),
+			Name: name.CasedName(),
+			Type: name.ArtifactType().Display(),
+		})
+	}
+	return families
+}
+
+// artifactQuery is the user facing form of db.DropQuery. Empty fields are not
+// filtered on.
+type artifactQuery struct {
+	// Family is either the enum name, e.g. ORNATE_GUSSET, or the in-game name,
+	// e.g. Gusset, case insensitive. Stone fragments are treated as the
+	// corresponding stone.
+	Family string `json:"family"`
+	Tier   int    `json:"tier"`
+	Rarity string `json:"rarity"`
+	// Since and Until are inclusive dates in local time, in the format
+	// YYYY-MM-DD.
+	Since string `json:"since"`
+	Until string `json:"until"`
+}
+
+type artifactDrop struct {
+	MissionId  string `json:"missionId"`
+	Ship       string `json:"ship"`
+	Type       string `json:"type"`
+	Level      uint32 `json:"level"`
+	LaunchedAt string `json:"launchedAt"`
+	ReturnedAt string `json:"returnedAt"`
+	// DropIndex is 1-based, matching the artifact columns of exports.
+	DropIndex int    `json:"dropIndex"`
+	Artifact  string `json:"artifact"`
+}
+
+func (q *artifactQuery) toDropQuery(playerId string) (db.DropQuery, error) {
+	dq := db.DropQuery{PlayerId: playerId}
+	if q.Family != "" {
+		family, err := parseArtifactFamily(q.Family)
+		if err != nil {
+			return dq, err
+		}
+		dq.Family = &family
+	}
+	if q.Tier < 0 || q.Tier > 4 {
+		return dq, errors.Errorf("invalid tier %d, expected 1 to 4", q.Tier)
+	}
+	dq.Tier = q.Tier
+	if q.Rarity != "" {
+		rarity, err := parseArtifactRarity(q.Rarity)
+		if err != nil {
+			return dq, err
+		}
+		dq.Rarity = &rarity
+	}
+	if q.Since != "" {
+		t, err := time.ParseInLocation("2006-01-02", q.Since, time.Local)
+		if err != nil {
+			return dq, errors.Wrapf(err, "invalid date %#v", q.Since)
+		}
+		dq.Since = timeToUnix(t)
+	}
+	if q.Until != "" {
+		t, err := time.ParseInLocation("2006-01-02", q.Until, time.Local)
+		if err != nil {
+			return dq, errors.Wrapf(err, "invalid date %#v", q.Until)
+		}
+		dq.Until = timeToUnix(t.AddDate(0, 0, 1))
+	}
+	return dq, nil
+}
+
+// lookupArtifactDrops answers "from which mission did I obtain this item?".
+func lookupArtifactDrops(playerId string, q *artifactQuery) ([]*artifactDrop, error) {
+	dq, err := q.toDropQuery(playerId)
+	if err != nil {
+		return nil, err
+	}
+	drops, err := db.QueryDrops(dq)
+	if err != nil {
+		return nil, err
+	}
+	var results []*artifactDrop
+	for _, d := range drops {
+		launchedAt := unixToTime(d.StartTimestamp).Truncate(time.Second)
+		returnedAt := launchedAt.Add(time.Duration(d.DurationSeconds) * time.Second)
+		results = append(results, &artifactDrop{
+			MissionId:  d.MissionId,
+			Ship:       d.Ship.Name(),
+			Type:       d.DurationType.Display(),
+			Level:      d.Level,
+			LaunchedAt: launchedAt.Format(time.RFC3339),
+			ReturnedAt: returnedAt.Format(time.RFC3339),
+			DropIndex:  d.DropIndex + 1,
+			Artifact:   d.Spec.Display(),
+		})
+	}
+	return results, nil
+}
+
+func normalizeEnumName(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.ReplaceAll(s, "'", "")
+	s = strings.ReplaceAll(s, "-", "_")
+	s = strings.ReplaceAll(s, " ", "_")
+	return s
+}
+
+func parseArtifactFamily(s string) (ei.ArtifactSpec_Name, error) {
+	normalized := normalizeEnumName(s)
+	for _, name := range ei.KnownArtifactNames() {
+		if name.Id() == normalized || normalizeEnumName(name.GameName()) == normalized {
+			return name.Family(), nil
+		}
+	}
+	return ei.ArtifactSpec_UNKNOWN, errors.Errorf("unknown artifact family %#v", s)
+}
+
+func parseArtifactRarity(s string) (ei.ArtifactSpec_Rarity, error) {
+	if value, ok := ei.ArtifactSpec_Rarity_value[normalizeEnumName(s)]; ok {
+		return ei.ArtifactSpec_Rarity(value), nil
+	}
+	return ei.ArtifactSpec_COMMON, errors.Errorf("unknown rarity %#v", s)
+}
+
+func parseShip(s string) (ei.MissionInfo_Spaceship, error) {
+	normalized := normalizeEnumName(s)
+	if value, ok := ei.MissionInfo_Spaceship_value[normalized]; ok {
+		return ei.MissionInfo_Spaceship(value), nil
+	}
+	for value := range ei.MissionInfo_Spaceship_name {
+		ship := ei.MissionInfo_Spaceship(value)
+		if normalizeEnumName(ship.Name()) == normalized {
+			return ship, nil
+		}
+	}
+	return ei.MissionInfo_CHICKEN_ONE, errors.Errorf("unknown ship %#v", s)
+}
+
+func parseDurationType(s string) (ei.MissionInfo_DurationType, error) {
+	normalized := normalizeEnumName(s)
+	if value, ok := ei.MissionInfo_DurationType_value[normalized]; ok {
+		return ei.MissionInfo_DurationType(value), nil
+	}
+	for value := range ei.MissionInfo_DurationType_name {
+		durationType := ei.MissionInfo_DurationType(value)
+		if normalizeEnumName(durationType.Display()) == normalized {
+			return durationType, nil
+		}
+	}
+	return ei.MissionInfo_SHORT, errors.Errorf("unknown duration type %#v", s)
+}
+
+func parseEgg(s string) (ei.Egg, error) {
+	normalized := normalizeEnumName(s)
+	if value, ok := ei.Egg_value[normalized]; ok {
+		return ei.Egg(value), nil
+	}
+	for value := range ei.Egg_name {
+		egg := ei.Egg(value)
+		if normalizeEnumName(egg.Display()) == normalized {
+			return egg, nil
+		}
+	}
+	return ei.Egg_EDIBLE, errors.Errorf("unknown egg %#v", s)
+}
+
+func parseRewardType(s string) (ei.RewardType, error) {
+	normalized := normalizeEnumName(s)
+	if value, ok := ei.RewardType_value[normalized]; ok {
+		return ei.RewardType(value), nil
+	}
+	for value := range ei.RewardType_name {
+		t := ei.RewardType(value)
+		if normalizeEnumName(t.Display()) == normalized {
+			return t, nil
+		}
+	}
+	return ei.RewardType_CASH, errors.Errorf("unknown reward type %#v", s)
+}
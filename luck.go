@@ -0,0 +1,249 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// The luck report compares the items actually dropped by each mission against
+// what could be expected, in two independent dimensions:
+//
+// Tier. The expected tier distribution is modeled from the artifacts
+// configuration: every item whose base quality is within the mission's quality
+// window can drop, with odds proportional to its odds multiplier. The window is
+// [min_quality, min(max_quality, q)] where q is the quality of the duration at
+// the mission's star level plus the mission's own quality bump. This is an
+// approximation; the actual loot algorithm is not public. Without an artifacts
+// configuration, the empirical tier distribution of the ship, duration and
+// level combination is used instead.
+//
+// Rarity. Rarity odds aren't part of the configuration, so the expected rarity
+// distribution is always empirical, i.e. the rarity rates of all drops from
+// missions with the same ship, duration and level.
+//
+// Luck is the difference between the actual and expected sums of tier numbers
+// and rarity values (common 0, rare 1, epic 2, legendary 3) over all drops.
+// A mission is flagged if its standardized luck is at least _luckFlagThreshold
+// standard deviations away from zero.
+
+const _luckFlagThreshold = 2
+
+type luckGroupKey struct {
+	shipDurationKey
+	Level uint32
+}
+
+// dropDistribution is the distribution of a numeric score of a single drop.
+type dropDistribution struct {
+	Mean     float64
+	Variance float64
+}
+
+func newDropDistribution(weights map[float64]float64) dropDistribution {
+	var total, mean float64
+	for value, weight := range weights {
+		total += weight
+		mean += value * weight
+	}
+	if total == 0 {
+		return dropDistribution{}
+	}
+	mean /= total
+	var variance float64
+	for value, weight := range weights {
+		variance += (value - mean) * (value - mean) * weight
+	}
+	variance /= total
+	return dropDistribution{Mean: mean, Variance: variance}
+}
+
+type missionLuck struct {
+	Mission        *mission
+	Drops          int
+	ExpectedTier   float64
+	ActualTier     float64
+	ExpectedRarity float64
+	ActualRarity   float64
+	// Score is the total luck standardized by its expected standard deviation.
+	Score float64
+	// Cumulative is the running sum of Score over all missions up to and
+	// including this one.
+	Cumulative float64
+}
+
+func (l *missionLuck) TierLuck() float64 {
+	return l.ActualTier - l.ExpectedTier
+}
+
+func (l *missionLuck) RarityLuck() float64 {
+	return l.ActualRarity - l.ExpectedRarity
+}
+
+func (l *missionLuck) Flagged() bool {
+	return math.Abs(l.Score) >= _luckFlagThreshold
+}
+
+type luckGroupSummary struct {
+	luckGroupKey
+	Missions       int
+	Drops          int
+	ExpectedTier   float64
+	ActualTier     float64
+	ExpectedRarity float64
+	ActualRarity   float64
+	// Modeled is true if the tier expectation is modeled from the artifacts
+	// configuration rather than empirical.
+	Modeled bool
+}
+
+type monthlyLuck struct {
+	// Month is the first instant of the month in local time.
+	Month      time.Time
+	Missions   int
+	Luck       float64
+	Cumulative float64
+}
+
+type luckReport struct {
+	Groups []*luckGroupSummary
+	// Unusual lists flagged missions, i.e. those that were unusually lucky or
+	// unlucky.
+	Unusual []*missionLuck
+	ByMonth []*monthlyLuck
+}
+
+func rarityValue(r ei.ArtifactSpec_Rarity) float64 {
+	return float64(r)
+}
+
+// modeledTierDistribution returns the modeled tier distribution of a single
+// drop from the mission, or false if it can't be modeled.
+func modeledTierDistribution(config *ei.ArtifactsConfigurationResponse, m *mission) (dropDistribution, bool) {
+	params := config.GetDurationParameters(m.Ship, m.DurationType)
+	if params == nil {
+		return dropDistribution{}, false
+	}
+	minQuality := float64(params.GetMinQuality())
+	maxQuality := math.Min(float64(params.GetMaxQuality()), params.QualityAtLevel(m.Level)+m.QualityBump)
+	weights := make(map[float64]float64)
+	for _, a := range config.GetArtifactParameters() {
+		spec := a.GetSpec()
+		// Each item is listed once per rarity; only consider one of them.
+		if spec == nil || spec.GetRarity() != ei.ArtifactSpec_COMMON {
+			continue
+		}
+		q := a.GetBaseQuality()
+		if q < minQuality || q > maxQuality {
+			continue
+		}
+		weights[float64(spec.TierNumber())] += a.GetOddsMultiplier()
+	}
+	if len(weights) == 0 {
+		return dropDistribution{}, false
+	}
+	return newDropDistribution(weights), true
+}
+
+// luckSummarizer builds a luckReport in two passes over the missions, since
+// the empirical distributions need all drops: every mission is prepared first,
+// then added in chronological order. config is optional.
+type luckSummarizer struct {
+	config *ei.ArtifactsConfigurationResponse
+	// Empirical distributions per group.
+	tierWeights   map[luckGroupKey]map[float64]float64
+	rarityWeights map[luckGroupKey]map[float64]float64
+	groups        map[luckGroupKey]*luckGroupSummary
+	report        luckReport
+	cumulative    float64
+}
+
+func newLuckSummarizer(config *ei.ArtifactsConfigurationResponse) *luckSummarizer {
+	return &luckSummarizer{
+		config:        config,
+		tierWeights:   make(map[luckGroupKey]map[float64]float64),
+		rarityWeights: make(map[luckGroupKey]map[float64]float64),
+		groups:        make(map[luckGroupKey]*luckGroupSummary),
+	}
+}
+
+func (s *luckSummarizer) prepare(m *mission) {
+	key := luckGroupKey{shipDurationKey{m.Ship, m.DurationType}, m.Level}
+	if _, ok := s.tierWeights[key]; !ok {
+		s.tierWeights[key] = make(map[float64]float64)
+		s.rarityWeights[key] = make(map[float64]float64)
+	}
+	for _, a := range m.Artifacts {
+		s.tierWeights[key][float64(a.TierNumber())]++
+		s.rarityWeights[key][rarityValue(a.GetRarity())]++
+	}
+}
+
+func (s *luckSummarizer) add(m *mission) {
+	key := luckGroupKey{shipDurationKey{m.Ship, m.DurationType}, m.Level}
+	tierDist, modeled := modeledTierDistribution(s.config, m)
+	if !modeled {
+		tierDist = newDropDistribution(s.tierWeights[key])
+	}
+	rarityDist := newDropDistribution(s.rarityWeights[key])
+
+	l := &missionLuck{
+		Mission: m,
+		Drops:   len(m.Artifacts),
+	}
+	n := float64(l.Drops)
+	l.ExpectedTier = n * tierDist.Mean
+	l.ExpectedRarity = n * rarityDist.Mean
+	for _, a := range m.Artifacts {
+		l.ActualTier += float64(a.TierNumber())
+		l.ActualRarity += rarityValue(a.GetRarity())
+	}
+	if stddev := math.Sqrt(n * (tierDist.Variance + rarityDist.Variance)); stddev > 0 {
+		l.Score = (l.TierLuck() + l.RarityLuck()) / stddev
+	}
+	s.cumulative += l.Score
+	l.Cumulative = s.cumulative
+	// Only flagged missions are kept, so that memory use doesn't grow with
+	// the number of missions.
+	if l.Flagged() {
+		s.report.Unusual = append(s.report.Unusual, l)
+	}
+
+	g, ok := s.groups[key]
+	if !ok {
+		g = &luckGroupSummary{luckGroupKey: key, Modeled: modeled}
+		s.groups[key] = g
+		s.report.Groups = append(s.report.Groups, g)
+	}
+	g.Missions++
+	g.Drops += l.Drops
+	g.ExpectedTier += l.ExpectedTier
+	g.ActualTier += l.ActualTier
+	g.ExpectedRarity += l.ExpectedRarity
+	g.ActualRarity += l.ActualRarity
+	g.Modeled = g.Modeled && modeled
+
+	year, month, _ := m.LaunchedAt.Date()
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, m.LaunchedAt.Location())
+	if len(s.report.ByMonth) == 0 || !s.report.ByMonth[len(s.report.ByMonth)-1].Month.Equal(monthStart) {
+		s.report.ByMonth = append(s.report.ByMonth, &monthlyLuck{Month: monthStart})
+	}
+	last := s.report.ByMonth[len(s.report.ByMonth)-1]
+	last.Missions++
+	last.Luck += l.Score
+	last.Cumulative = s.cumulative
+}
+
+func (s *luckSummarizer) summary() *luckReport {
+	report := &s.report
+	sort.Slice(report.Groups, func(i, j int) bool {
+		gi, gj := report.Groups[i], report.Groups[j]
+		if gi.shipDurationKey != gj.shipDurationKey {
+			return gi.less(gj.shipDurationKey)
+		}
+		return gi.Level < gj.Level
+	})
+	return report
+}
@@ -19,6 +19,7 @@ import (
 
 	humanize "github.com/dustin/go-humanize"
 	"github.com/fanaticscripter/EggLedger/db"
+	"github.com/fanaticscripter/EggLedger/ei"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/writer"
@@ -194,11 +195,27 @@ func init() {
 		})
 	}
 
+	// Lets users fix up artifact names and types after a game update, before
+	// the app catches up.
+	if err := ei.LoadCatalogOverride(filepath.Join(_rootDir, "catalog.json")); err != nil {
+		log.Error(err)
+	} else {
+		log.Infof("artifact catalog version: %d", ei.CatalogVersion())
+	}
+
+	localesInit(filepath.Join(_rootDir, "locales"))
 	storageInit()
-	dataInit()
+	_dbPath = filepath.Join(_internalDir, "data.db")
 }
 
 func main() {
+	if isCommand(os.Args[1:]) {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+	if err := dataInit(); err != nil {
+		log.Fatal(err)
+	}
+
 	if _devMode {
 		log.Info("starting app in dev mode")
 	}
@@ -262,6 +279,10 @@ func main() {
 		log.Info(args...)
 		emitMessage(fmt.Sprint(args...), false)
 	}
+	pwarn := func(args ...interface{}) {
+		log.Warn(args...)
+		emitMessage(fmt.Sprint(args...), false)
+	}
 	perror := func(args ...interface{}) {
 		log.Error(args...)
 		emitMessage(fmt.Sprint(args...), true)
@@ -284,9 +305,43 @@ func main() {
 	})
 
 	ui.MustBind("knownAccounts", func() []Account {
+		return _storage.Accounts()
+	})
+
+	ui.MustBind("exportLanguages", func() []*exportLanguage {
+		return exportLanguages()
+	})
+
+	ui.MustBind("exportLanguage", func() string {
 		_storage.Lock()
 		defer _storage.Unlock()
-		return _storage.KnownAccounts
+		return getLocalizer(_storage.ExportLanguage).code
+	})
+
+	ui.MustBind("setExportLanguage", func(code string) {
+		_storage.SetExportLanguage(code)
+	})
+
+	ui.MustBind("artifactFamilies", func() []*artifactFamily {
+		return artifactFamilies()
+	})
+
+	ui.MustBind("lookupArtifactDrops", func(playerId string, q artifactQuery) ([]*artifactDrop, error) {
+		drops, err := lookupArtifactDrops(playerId, &q)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		return drops, nil
+	})
+
+	ui.MustBind("planMissions", func(playerId string, q artifactQuery, rank string) ([]*missionPlan, error) {
+		plans, err := planMissionsForTarget(playerId, &q, rank)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		return plans, nil
 	})
 
 	w := &worker{
@@ -345,13 +400,26 @@ func main() {
 				perror("backup is from unknown time")
 			}
 			_storage.AddKnownAccount(Account{Id: playerId, Nickname: nickname})
-			_storage.Lock()
-			updateKnownAccounts(_storage.KnownAccounts)
-			_storage.Unlock()
+			updateKnownAccounts(_storage.Accounts())
 			if checkInterrupt() {
 				return
 			}
 
+			var backupTime time.Time
+			if lastBackupTime != 0 {
+				backupTime = unixToTime(lastBackupTime)
+			} else {
+				backupTime = time.Now()
+			}
+			var activeMissions []*activeMission
+			for _, info := range fc.GetInProgressMissions() {
+				activeMissions = append(activeMissions, newActiveMission(info, backupTime, time.Now()))
+			}
+			pinfo(fmt.Sprintf("found %d active missions", len(activeMissions)))
+			for _, m := range activeMissions {
+				pinfo(m.Summary())
+			}
+
 			missions := fc.GetCompletedMissions()
 			existingMissionIds, err := db.RetrievePlayerCompleteMissionIds(playerId)
 			if err != nil {
@@ -431,17 +499,40 @@ func main() {
 				}
 			}
 
+			// The artifacts configuration is only used to annotate reports, so
+			// failing to fetch it isn't fatal.
+			config, err := fetchArtifactsConfigurationWithContext(ctx, playerId)
+			if err != nil {
+				log.Error(err)
+			}
+			if checkInterrupt() {
+				return
+			}
+
 			updateState(AppState_EXPORTING_DATA)
-			completeMissions, err := db.RetrievePlayerCompleteMissions(playerId)
+			importedMissionIds, err := db.RetrievePlayerImportedMissionIds(playerId)
 			if err != nil {
 				perror(err)
 				updateState(AppState_FAILED)
 				return
 			}
-			var exportMissions []*mission
-			for _, m := range completeMissions {
-				exportMissions = append(exportMissions, newMission(m))
+			// Missions are decoded from the database on each pass over them
+			// rather than all held in memory, which large accounts can't afford.
+			// Passes are shared by all exports, see shareMissionPasses.
+			exportMissions := func(f func(*mission) error) error {
+				return db.IteratePlayerCompleteMissions(playerId, func(m *ei.CompleteMissionResponse) error {
+					exportMission := newMission(m, config)
+					exportMission.Imported = importedMissionIds[exportMission.Id]
+					return f(exportMission)
+				})
 			}
+			reports := &reportInputs{
+				ActiveMissions: activeMissions,
+				Config:         config,
+			}
+			_storage.Lock()
+			exportLocalizer := getLocalizer(_storage.ExportLanguage)
+			_storage.Unlock()
 			if checkInterrupt() {
 				return
 			}
@@ -472,20 +563,38 @@ func main() {
 			filenameTimestamp := time.Now().Format("20060102_150405")
 
 			xlsxFile := filepath.Join(exportDir, playerId+"."+filenameTimestamp+".xlsx")
-			if err := exportMissionsToXlsx(exportMissions, xlsxFile); err != nil {
-				perror(err)
-				updateState(AppState_FAILED)
-				return
-			}
-			if checkInterrupt() {
-				return
-			}
-
 			csvFile := filepath.Join(exportDir, playerId+"."+filenameTimestamp+".csv")
-			if err := exportMissionsToCsv(exportMissions, csvFile); err != nil {
-				perror(err)
-				updateState(AppState_FAILED)
-				return
+			// The calendar is always written to the same path since events carry
+			// stable UIDs; re-importing it updates previously imported events.
+			icsFile := filepath.Join(exportDir, playerId+".ics")
+			var unknownValuesWarning string
+			errs := shareMissionPasses(exportMissions,
+				func(missions missionIterator) (err error) {
+					unknownValuesWarning, err = checkUnknownValues(playerId, missions)
+					return
+				},
+				func(missions missionIterator) error {
+					return exportMissionsToXlsx(missions, reports, exportLocalizer, xlsxFile)
+				},
+				func(missions missionIterator) error {
+					return exportMissionsToCsv(missions, exportLocalizer, csvFile)
+				},
+				func(missions missionIterator) error {
+					return exportMissionsToIcs(playerId, missions, icsFile)
+				},
+			)
+			// Values the app can't handle don't prevent exporting.
+			if errs[0] != nil {
+				log.Error(errs[0])
+			} else if unknownValuesWarning != "" {
+				pwarn(unknownValuesWarning)
+			}
+			for _, err := range errs[1:] {
+				if err != nil {
+					perror(err)
+					updateState(AppState_FAILED)
+					return
+				}
 			}
 			if checkInterrupt() {
 				return
@@ -529,7 +638,47 @@ func main() {
 			}
 			xlsxFileRel, _ := filepath.Rel(_rootDir, xlsxFile)
 			csvFileRel, _ := filepath.Rel(_rootDir, csvFile)
-			updateExportedFiles([]string{xlsxFileRel, csvFileRel})
+			icsFileRel, _ := filepath.Rel(_rootDir, icsFile)
+			exportedFiles := []string{xlsxFileRel, csvFileRel, icsFileRel}
+
+			// Active missions change with every sync, so only the latest report is
+			// kept, at a stable path that calendar apps can subscribe to.
+			activeDir := filepath.Join(_rootDir, "exports", "active")
+			if err := os.MkdirAll(activeDir, 0755); err != nil {
+				perror(errors.Wrap(err, "failed to create export directory"))
+				updateState(AppState_FAILED)
+				return
+			}
+			activeCsvFile := filepath.Join(activeDir, playerId+".csv")
+			if err := exportActiveMissionsToCsv(activeMissions, activeCsvFile); err != nil {
+				perror(err)
+				updateState(AppState_FAILED)
+				return
+			}
+			activeIcsFile := filepath.Join(activeDir, playerId+".ics")
+			if err := exportActiveMissionsToIcs(playerId, activeMissions, activeIcsFile); err != nil {
+				perror(err)
+				updateState(AppState_FAILED)
+				return
+			}
+			activeCsvFileRel, _ := filepath.Rel(_rootDir, activeCsvFile)
+			activeIcsFileRel, _ := filepath.Rel(_rootDir, activeIcsFile)
+			exportedFiles = append(exportedFiles, activeCsvFileRel, activeIcsFileRel)
+			updateExportedFiles(exportedFiles)
+
+			// The sync itself succeeded, so failed housekeeping is only
+			// logged. Compaction can take a while on a large database, so it
+			// carries on in the background after the sync is reported done.
+			if _, err := db.BackupDatabase(db.DatabaseBackupReason_SYNC); err != nil {
+				log.Error(err)
+			}
+			db.StartBackgroundCompaction()
+			if missionCount, err := db.CountPlayerCompleteMissions(playerId); err != nil {
+				log.Error(err)
+			} else {
+				_storage.RecordAccountSync(playerId, time.Now(), missionCount)
+				updateKnownAccounts(_storage.Accounts())
+			}
 
 			pinfo("done.")
 			updateState(AppState_SUCCESS)
@@ -544,6 +693,53 @@ func main() {
 		}
 	})
 
+	ui.MustBind("updateAccount", func(playerId string, edit AccountEdit) error {
+		if err := _storage.UpdateAccount(playerId, edit); err != nil {
+			log.Error(err)
+			return err
+		}
+		updateKnownAccounts(_storage.Accounts())
+		return nil
+	})
+
+	ui.MustBind("moveAccount", func(playerId string, delta int) error {
+		if err := _storage.MoveAccount(playerId, delta); err != nil {
+			log.Error(err)
+			return err
+		}
+		updateKnownAccounts(_storage.Accounts())
+		return nil
+	})
+
+	ui.MustBind("forgetAccount", func(playerId string, keepArchive bool) error {
+		// Shares the worker with syncs, which would otherwise write the
+		// account's data back.
+		if !w.TryAcquire(1) {
+			err := errors.New("currently fetching player data, try again when done")
+			perror(err)
+			return err
+		}
+		defer w.Release(1)
+		var archivePath string
+		if keepArchive {
+			archivePath = filepath.Join(_rootDir, "archives",
+				playerId+"."+time.Now().Format("20060102_150405")+".zip")
+		}
+		result, err := forgetAccount(playerId, archivePath)
+		if err != nil {
+			perror(err)
+			return err
+		}
+		updateKnownAccounts(_storage.Accounts())
+		pinfo(fmt.Sprintf("forgot account %s: deleted %d missions and %d backups, removed %d exported files",
+			playerId, result.Missions, result.Backups, len(result.RemovedFiles)))
+		if result.Archive != "" {
+			archiveRel, _ := filepath.Rel(_rootDir, result.Archive)
+			pinfo(fmt.Sprintf("archive of its data kept at %s", archiveRel))
+		}
+		return nil
+	})
+
 	ui.MustBind("openFile", func(file string) {
 		path := filepath.Join(_rootDir, file)
 		if err := open.Start(path); err != nil {
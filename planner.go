@@ -0,0 +1,234 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/fanaticscripter/EggLedger/db"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// _planConfidenceZ is the z-score of the confidence intervals reported by the
+// planner (95%).
+const _planConfidenceZ = 1.96
+
+// missionPlan is the historical performance of a ship, duration and level
+// combination at dropping a target item. Drop counts are modeled as Poisson, so
+// the uncertainty only depends on the number of matching drops; the intervals
+// are Poisson score intervals, which stay meaningful when nothing has dropped
+// yet.
+type missionPlan struct {
+	Ship     string  `json:"ship"`
+	Type     string  `json:"type"`
+	Level    uint32  `json:"level"`
+	Missions int     `json:"missions"`
+	Drops    int     `json:"drops"`
+	Hours    float64 `json:"hours"`
+	// Fuels lists the fuel spent by egg type, in enum order. Amounts of
+	// different eggs aren't comparable, so they are never added up.
+	Fuels []*planFuel `json:"fuels"`
+
+	DropsPerMission     float64 `json:"dropsPerMission"`
+	DropsPerMissionLow  float64 `json:"dropsPerMissionLow"`
+	DropsPerMissionHigh float64 `json:"dropsPerMissionHigh"`
+	DropsPerHour        float64 `json:"dropsPerHour"`
+	DropsPerHourLow     float64 `json:"dropsPerHourLow"`
+	DropsPerHourHigh    float64 `json:"dropsPerHourHigh"`
+	// FuelPerDropStr lists the fuel spent per drop of each egg type, empty if
+	// nothing has dropped.
+	FuelPerDropStr string `json:"fuelPerDropStr"`
+
+	fuel fuelTotals
+}
+
+type planFuel struct {
+	Egg    string  `json:"egg"`
+	Amount float64 `json:"amount"`
+	// PerDrop is 0 if nothing has dropped.
+	PerDrop float64 `json:"perDrop"`
+
+	egg ei.Egg
+}
+
+// fuelPerDrop returns the amount of the egg spent per drop, and whether the
+// egg has been spent at all.
+func (p *missionPlan) fuelPerDrop(egg ei.Egg) (float64, bool) {
+	for _, f := range p.Fuels {
+		if f.egg == egg {
+			return f.PerDrop, true
+		}
+	}
+	return 0, false
+}
+
+// poissonInterval returns the score interval of the rate of a Poisson process
+// with count events observed over the given exposure.
+func poissonInterval(count int, exposure float64) (low, high float64) {
+	if exposure == 0 {
+		return 0, 0
+	}
+	k := float64(count)
+	z := _planConfidenceZ
+	center := k + z*z/2
+	spread := z * math.Sqrt(k+z*z/4)
+	return math.Max(center-spread, 0) / exposure, (center + spread) / exposure
+}
+
+// matchesDropQuery reports whether the item satisfies the item filters of the
+// query, i.e. family, tier and rarity.
+func matchesDropQuery(q db.DropQuery, a *ei.ArtifactSpec) bool {
+	if q.Family != nil && a.Family() != *q.Family {
+		return false
+	}
+	if q.Tier != 0 && a.TierNumber() != q.Tier {
+		return false
+	}
+	if q.Rarity != nil && a.GetRarity() != *q.Rarity {
+		return false
+	}
+	return true
+}
+
+// missionPlanner builds mission plans from missions added one by one, so that
+// missions don't have to be kept in memory.
+type missionPlanner struct {
+	q     db.DropQuery
+	byKey map[luckGroupKey]*missionPlan
+	keys  []luckGroupKey
+}
+
+func newMissionPlanner(q db.DropQuery) *missionPlanner {
+	return &missionPlanner{
+		q:     q,
+		byKey: make(map[luckGroupKey]*missionPlan),
+	}
+}
+
+// add accounts for a mission, unless it's outside the time bounds of the query.
+func (s *missionPlanner) add(m *mission) {
+	launchedAt := timeToUnix(m.LaunchedAt)
+	if (s.q.Since != 0 && launchedAt < s.q.Since) || (s.q.Until != 0 && launchedAt >= s.q.Until) {
+		return
+	}
+	key := luckGroupKey{shipDurationKey{m.Ship, m.DurationType}, m.Level}
+	p, ok := s.byKey[key]
+	if !ok {
+		p = &missionPlan{
+			Ship:  m.ShipName,
+			Type:  m.DurationTypeName,
+			Level: m.Level,
+			fuel:  make(fuelTotals),
+		}
+		s.byKey[key] = p
+		s.keys = append(s.keys, key)
+	}
+	p.Missions++
+	p.Hours += m.Duration.Hours()
+	p.fuel.add(m.Fuels)
+	for _, a := range m.Artifacts {
+		if matchesDropQuery(s.q, a) {
+			p.Drops++
+		}
+	}
+}
+
+// plans ranks the combinations by drops per hour, or if fuelEgg isn't nil, by
+// the amount of that egg spent per drop. When ranking by fuel, combinations
+// that don't burn the egg come after those that do, and combinations without
+// any drop go last.
+func (s *missionPlanner) plans(fuelEgg *ei.Egg) []*missionPlan {
+	var plans []*missionPlan
+	for _, key := range s.keys {
+		p := s.byKey[key]
+		p.DropsPerMission = float64(p.Drops) / float64(p.Missions)
+		p.DropsPerMissionLow, p.DropsPerMissionHigh = poissonInterval(p.Drops, float64(p.Missions))
+		if p.Hours > 0 {
+			p.DropsPerHour = float64(p.Drops) / p.Hours
+			p.DropsPerHourLow, p.DropsPerHourHigh = poissonInterval(p.Drops, p.Hours)
+		}
+		var perDrop []string
+		for _, egg := range p.fuel.eggs() {
+			f := &planFuel{
+				Egg:    egg.Display(),
+				Amount: p.fuel[egg],
+				egg:    egg,
+			}
+			if p.Drops > 0 {
+				f.PerDrop = f.Amount / float64(p.Drops)
+				perDrop = append(perDrop, formatEggAmount(f.PerDrop)+" "+f.Egg)
+			}
+			p.Fuels = append(p.Fuels, f)
+		}
+		p.FuelPerDropStr = strings.Join(perDrop, ", ")
+		plans = append(plans, p)
+	}
+	sort.SliceStable(plans, func(i, j int) bool {
+		pi, pj := plans[i], plans[j]
+		if fuelEgg != nil {
+			if (pi.Drops == 0) != (pj.Drops == 0) {
+				return pj.Drops == 0
+			}
+			fi, burnsI := pi.fuelPerDrop(*fuelEgg)
+			fj, burnsJ := pj.fuelPerDrop(*fuelEgg)
+			if burnsI != burnsJ {
+				return burnsI
+			}
+			if fi != fj {
+				return fi < fj
+			}
+		} else if pi.DropsPerHour != pj.DropsPerHour {
+			return pi.DropsPerHour > pj.DropsPerHour
+		}
+		// More data is more trustworthy.
+		return pi.Missions > pj.Missions
+	})
+	return plans
+}
+
+// parsePlanRank parses a planner ranking, either "hour" (the default) or
+// "fuel:<egg>", returning the egg to rank by in the latter case.
+func parsePlanRank(rank string) (*ei.Egg, error) {
+	switch {
+	case rank == "" || rank == "hour":
+		return nil, nil
+	case strings.HasPrefix(rank, "fuel:"):
+		egg, err := parseEgg(strings.TrimPrefix(rank, "fuel:"))
+		if err != nil {
+			return nil, err
+		}
+		return &egg, nil
+	case rank == "fuel":
+		return nil, errors.New("ranking by fuel needs an egg type, e.g. fuel:dilithium")
+	default:
+		return nil, errors.Errorf("unknown ranking %#v, expected hour or fuel:<egg>", rank)
+	}
+}
+
+// planMissionsForTarget ranks the player's mission combinations for dropping
+// items matching the query. rank is either "hour" (the default) or
+// "fuel:<egg>".
+func planMissionsForTarget(playerId string, q *artifactQuery, rank string) ([]*missionPlan, error) {
+	if q.Family == "" && q.Tier == 0 && q.Rarity == "" {
+		return nil, errors.New("target item not specified")
+	}
+	fuelEgg, err := parsePlanRank(rank)
+	if err != nil {
+		return nil, err
+	}
+	dq, err := q.toDropQuery(playerId)
+	if err != nil {
+		return nil, err
+	}
+	planner := newMissionPlanner(dq)
+	err = db.IteratePlayerCompleteMissions(playerId, func(m *ei.CompleteMissionResponse) error {
+		planner.add(newMission(m, nil))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return planner.plans(fuelEgg), nil
+}
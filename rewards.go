@@ -0,0 +1,114 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// rewardKey identifies a kind of non-artifact mission reward. SubType further
+// qualifies some reward types, e.g. the identifier of a boost; it's empty for
+// most types.
+type rewardKey struct {
+	Type    ei.RewardType
+	SubType string
+}
+
+func (k rewardKey) Display() string {
+	if k.SubType == "" {
+		return k.Type.Display()
+	}
+	return k.Type.Display() + " (" + k.SubType + ")"
+}
+
+func (k rewardKey) less(other rewardKey) bool {
+	if k.Type != other.Type {
+		return k.Type < other.Type
+	}
+	return k.SubType < other.SubType
+}
+
+// rewardTotals maps kinds of rewards to total amounts.
+type rewardTotals map[rewardKey]float64
+
+func (t rewardTotals) add(rewards []*ei.Reward) {
+	for _, r := range rewards {
+		t[rewardKey{r.GetRewardType(), r.GetRewardSubType()}] += r.GetRewardAmount()
+	}
+}
+
+type monthlyRewardSummary struct {
+	// Month is the first instant of the month in local time.
+	Month    time.Time
+	Missions int
+	Rewards  rewardTotals
+}
+
+type rewardSummary struct {
+	// Keys lists all kinds of rewards ever received, in enum order.
+	Keys    []rewardKey
+	Totals  rewardTotals
+	ByMonth []*monthlyRewardSummary
+}
+
+// rewardSummarizer builds a rewardSummary from missions added in chronological
+// order.
+type rewardSummarizer struct {
+	keys   rewardKeySet
+	totals rewardTotals
+	months []*monthlyRewardSummary
+}
+
+func newRewardSummarizer() *rewardSummarizer {
+	return &rewardSummarizer{
+		keys:   make(rewardKeySet),
+		totals: make(rewardTotals),
+	}
+}
+
+func (s *rewardSummarizer) add(m *mission) {
+	s.keys.add(m.OtherRewards)
+	s.totals.add(m.OtherRewards)
+
+	year, month, _ := m.LaunchedAt.Date()
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, m.LaunchedAt.Location())
+	if len(s.months) == 0 || !s.months[len(s.months)-1].Month.Equal(monthStart) {
+		s.months = append(s.months, &monthlyRewardSummary{
+			Month:   monthStart,
+			Rewards: make(rewardTotals),
+		})
+	}
+	last := s.months[len(s.months)-1]
+	last.Missions++
+	last.Rewards.add(m.OtherRewards)
+}
+
+func (s *rewardSummarizer) summary() *rewardSummary {
+	return &rewardSummary{
+		Keys:    s.keys.sorted(),
+		Totals:  s.totals,
+		ByMonth: s.months,
+	}
+}
+
+// rewardKeySet collects kinds of rewards received.
+type rewardKeySet map[rewardKey]struct{}
+
+func (s rewardKeySet) add(rewards []*ei.Reward) {
+	for _, r := range rewards {
+		s[rewardKey{r.GetRewardType(), r.GetRewardSubType()}] = struct{}{}
+	}
+}
+
+// sorted returns the kinds of rewards in enum order.
+func (s rewardKeySet) sorted() []rewardKey {
+	var keys []rewardKey
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+	return keys
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// shipLevelMilestone records when a ship was first launched at a star level.
+type shipLevelMilestone struct {
+	Ship      ei.MissionInfo_Spaceship
+	Level     uint32
+	ReachedAt time.Time
+	Launches  int
+	// LaunchPoints weighs Launches by duration.
+	LaunchPoints float64
+}
+
+type shipStatus struct {
+	Ship            ei.MissionInfo_Spaceship
+	Launches        int
+	Level           uint32
+	LaunchesAtLevel int
+	// LaunchPointsAtLevel weighs launches at the current level by duration,
+	// see ei.MissionInfo_DurationType.LaunchPoints.
+	LaunchPointsAtLevel float64
+	// RequiredLaunchPoints is the number of launch points at the current level
+	// required to reach the next level, or 0 if unknown (no artifacts
+	// configuration) or the ship is already at max level.
+	RequiredLaunchPoints float64
+	MaxLevel             bool
+}
+
+// RemainingLaunchPoints returns the number of launch points until the next
+// level, or -1 if unknown.
+func (s *shipStatus) RemainingLaunchPoints() float64 {
+	if s.MaxLevel {
+		return 0
+	}
+	if s.RequiredLaunchPoints == 0 {
+		return -1
+	}
+	return math.Max(s.RequiredLaunchPoints-s.LaunchPointsAtLevel, 0)
+}
+
+// RemainingLaunches returns the projected number of launches until the next
+// level, assuming the same mix of durations as so far at this level, or -1 if
+// unknown.
+func (s *shipStatus) RemainingLaunches() int {
+	remaining := s.RemainingLaunchPoints()
+	if remaining <= 0 {
+		return int(remaining)
+	}
+	pointsPerLaunch := 1.0
+	if s.LaunchesAtLevel > 0 {
+		pointsPerLaunch = s.LaunchPointsAtLevel / float64(s.LaunchesAtLevel)
+	}
+	// Guard against 2.8-2.8 style rounding errors making up a launch.
+	return int(math.Ceil(remaining/pointsPerLaunch - 1e-9))
+}
+
+type monthlyLaunches struct {
+	// Month is the first instant of the month in local time.
+	Month    time.Time
+	Launches map[ei.MissionInfo_Spaceship]int
+}
+
+type shipProgression struct {
+	// Ships lists all ships ever launched, in enum order.
+	Ships      []ei.MissionInfo_Spaceship
+	Milestones []*shipLevelMilestone
+	Statuses   []*shipStatus
+	ByMonth    []*monthlyLaunches
+}
+
+type milestoneKey struct {
+	ship  ei.MissionInfo_Spaceship
+	level uint32
+}
+
+// shipProgressionSummarizer reconstructs launch counts and star levels of each
+// ship from launches of completed and active missions, added in any order.
+type shipProgressionSummarizer struct {
+	milestones map[milestoneKey]*shipLevelMilestone
+	statuses   map[ei.MissionInfo_Spaceship]*shipStatus
+	// byMonth is keyed by the Unix time of Month.
+	byMonth map[int64]*monthlyLaunches
+}
+
+func newShipProgressionSummarizer() *shipProgressionSummarizer {
+	return &shipProgressionSummarizer{
+		milestones: make(map[milestoneKey]*shipLevelMilestone),
+		statuses:   make(map[ei.MissionInfo_Spaceship]*shipStatus),
+		byMonth:    make(map[int64]*monthlyLaunches),
+	}
+}
+
+func (s *shipProgressionSummarizer) add(m *mission) {
+	s.addLaunch(m.Ship, m.DurationType, m.Level, m.LaunchedAt)
+}
+
+func (s *shipProgressionSummarizer) addActive(m *activeMission) {
+	s.addLaunch(m.Ship, m.DurationType, m.Level, m.LaunchedAt)
+}
+
+func (s *shipProgressionSummarizer) addLaunch(ship ei.MissionInfo_Spaceship, durationType ei.MissionInfo_DurationType,
+	level uint32, launchedAt time.Time) {
+	key := milestoneKey{ship, level}
+	milestone, ok := s.milestones[key]
+	if !ok {
+		milestone = &shipLevelMilestone{
+			Ship:      ship,
+			Level:     level,
+			ReachedAt: launchedAt,
+		}
+		s.milestones[key] = milestone
+	} else if launchedAt.Before(milestone.ReachedAt) {
+		milestone.ReachedAt = launchedAt
+	}
+	milestone.Launches++
+	milestone.LaunchPoints += durationType.LaunchPoints()
+
+	status, ok := s.statuses[ship]
+	if !ok {
+		status = &shipStatus{Ship: ship}
+		s.statuses[ship] = status
+	}
+	status.Launches++
+	if level >= status.Level {
+		status.Level = level
+	}
+
+	year, month, _ := launchedAt.Date()
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, launchedAt.Location())
+	monthly, ok := s.byMonth[monthStart.Unix()]
+	if !ok {
+		monthly = &monthlyLaunches{
+			Month:    monthStart,
+			Launches: make(map[ei.MissionInfo_Spaceship]int),
+		}
+		s.byMonth[monthStart.Unix()] = monthly
+	}
+	monthly.Launches[ship]++
+}
+
+// summary returns the progression of each ship launched so far. config is
+// optional; without it, launches required for the next level are unknown.
+func (s *shipProgressionSummarizer) summary(config *ei.ArtifactsConfigurationResponse) *shipProgression {
+	var p shipProgression
+	for ship := range s.statuses {
+		p.Ships = append(p.Ships, ship)
+	}
+	sort.Slice(p.Ships, func(i, j int) bool {
+		return p.Ships[i] < p.Ships[j]
+	})
+	for _, milestone := range s.milestones {
+		p.Milestones = append(p.Milestones, milestone)
+	}
+	sort.Slice(p.Milestones, func(i, j int) bool {
+		if p.Milestones[i].Ship != p.Milestones[j].Ship {
+			return p.Milestones[i].Ship < p.Milestones[j].Ship
+		}
+		return p.Milestones[i].Level < p.Milestones[j].Level
+	})
+	for _, monthly := range s.byMonth {
+		p.ByMonth = append(p.ByMonth, monthly)
+	}
+	sort.Slice(p.ByMonth, func(i, j int) bool {
+		return p.ByMonth[i].Month.Before(p.ByMonth[j].Month)
+	})
+	for _, ship := range p.Ships {
+		status := s.statuses[ship]
+		current := s.milestones[milestoneKey{ship, status.Level}]
+		status.LaunchesAtLevel = current.Launches
+		status.LaunchPointsAtLevel = current.LaunchPoints
+		if params := config.GetShipParameters(ship); params != nil {
+			requirements := params.GetLevelMissionRequirements()
+			switch {
+			case len(requirements) == 0:
+				// Unknown.
+			case int(status.Level) >= len(requirements):
+				status.MaxLevel = true
+			default:
+				status.RequiredLaunchPoints = float64(requirements[status.Level])
+			}
+		}
+		p.Statuses = append(p.Statuses, status)
+	}
+	return &p
+}
@@ -4,24 +4,73 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
+// _storageVersion is the version of the storage.json format. Files written by
+// older versions of the app are migrated when loaded, and no copy is kept:
+// older versions ignore fields they don't know about, so migrations must only
+// add fields. Files written by newer versions are never overwritten.
+const _storageVersion = 1
+
+// _storageMigrations[i] migrates storage from version i to i+1.
+var _storageMigrations = []func(s *AppStorage){
+	// Accounts gained an alias, pinning, tags, notes and sync statistics, all
+	// of which start out empty; nicknames remain the in-game names. Mission
+	// counts are filled in from the database once it's open, see
+	// fillAccountMissionCounts.
+	func(s *AppStorage) {},
+}
+
 type AppStorage struct {
 	sync.Mutex
 
+	Version int `json:"version"`
+	// readOnly is set when storage.json was written by a newer version of
+	// the app, whose settings would be lost if it were saved.
+	readOnly bool
+
+	// KnownAccounts lists pinned accounts first, in the order set by the
+	// user, then the others, most recently synced first.
 	KnownAccounts []Account `json:"known_accounts"`
 
 	LastUpdateCheckAt  time.Time `json:"last_update_check_at"`
 	KnownLatestVersion string    `json:"known_latest_version"`
+
+	// ExportLanguage is the code of the language of mission exports; empty
+	// means English.
+	ExportLanguage string `json:"export_language"`
 }
 
 type Account struct {
-	Id       string `json:"id"`
+	Id string `json:"id"`
+	// Nickname is the in-game name, as of the latest sync.
 	Nickname string `json:"nickname"`
+
+	// Alias, Pinned, Tags and Notes are set by the user, and kept across
+	// syncs. Alias is shown instead of the nickname if set.
+	Alias  string   `json:"alias,omitempty"`
+	Pinned bool     `json:"pinned,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Notes  string   `json:"notes,omitempty"`
+
+	// LastSyncedAt is the time of the last successful sync, zero if unknown,
+	// and MissionCount the number of missions stored at that time.
+	LastSyncedAt time.Time `json:"last_synced_at"`
+	MissionCount int       `json:"mission_count"`
+}
+
+// AccountEdit holds the user-defined fields of an account.
+type AccountEdit struct {
+	Alias  string   `json:"alias"`
+	Pinned bool     `json:"pinned"`
+	Tags   []string `json:"tags"`
+	Notes  string   `json:"notes"`
 }
 
 var (
@@ -36,21 +85,46 @@ func storageInit() {
 
 func (s *AppStorage) Load() {
 	s.Lock()
-	defer s.Unlock()
 	encoded, err := os.ReadFile(_storageFile)
 	if err != nil {
+		// Nothing to migrate in a fresh installation.
+		s.Version = _storageVersion
+		s.Unlock()
 		log.Errorf("error loading storage.json: %s", err)
 		return
 	}
+	// Files older than the version field have none.
+	s.Version = 0
 	if err := json.Unmarshal(encoded, &s); err != nil {
+		s.Unlock()
 		log.Errorf("error parsing storage.json: %s", err)
 		return
 	}
+	if s.Version > _storageVersion {
+		s.readOnly = true
+		s.Unlock()
+		log.Warnf("storage.json has version %d, which is newer than this version of EggLedger supports (%d); "+
+			"changes to accounts and settings won't be saved", s.Version, _storageVersion)
+		return
+	}
+	if s.Version == _storageVersion {
+		s.Unlock()
+		return
+	}
+	for ; s.Version < _storageVersion; s.Version++ {
+		_storageMigrations[s.Version](s)
+	}
+	log.Infof("migrated storage.json to version %d", s.Version)
+	s.Unlock()
+	s.Persist()
 }
 
 func (s *AppStorage) Persist() {
 	s.Lock()
 	defer s.Unlock()
+	if s.readOnly {
+		return
+	}
 	encoded, err := json.Marshal(s)
 	if err != nil {
 		log.Errorf("error serializing app storage: %s", err)
@@ -61,19 +135,247 @@ func (s *AppStorage) Persist() {
 	}
 }
 
+// Accounts returns a copy of the known accounts.
+func (s *AppStorage) Accounts() []Account {
+	s.Lock()
+	defer s.Unlock()
+	accounts := make([]Account, len(s.KnownAccounts))
+	copy(accounts, s.KnownAccounts)
+	return accounts
+}
+
+// AddKnownAccount records the nickname of an account being synced, and moves
+// it to the front of unpinned accounts. Everything else already known about
+// the account is kept.
 func (s *AppStorage) AddKnownAccount(account Account) {
 	s.Lock()
-	accounts := []Account{account}
-	seen := map[string]struct{}{account.Id: {}}
+	if i := s.findAccount(account.Id); i >= 0 {
+		existing := s.KnownAccounts[i]
+		existing.Nickname = account.Nickname
+		account = existing
+	}
+	if account.Pinned {
+		s.KnownAccounts[s.findAccount(account.Id)] = account
+	} else {
+		s.placeAccount(account)
+	}
+	s.Unlock()
+	go s.Persist()
+}
+
+// RecordAccountSync records a successful sync of a known account.
+func (s *AppStorage) RecordAccountSync(playerId string, at time.Time, missionCount int) {
+	s.Lock()
+	if i := s.findAccount(playerId); i >= 0 {
+		s.KnownAccounts[i].LastSyncedAt = at
+		s.KnownAccounts[i].MissionCount = missionCount
+	}
+	s.Unlock()
+	go s.Persist()
+}
+
+// UpdateAccount sets the user-defined fields of a known account. Newly pinned
+// accounts go last among pinned accounts, and newly unpinned ones first among
+// the others.
+func (s *AppStorage) UpdateAccount(playerId string, edit AccountEdit) error {
+	s.Lock()
+	i := s.findAccount(playerId)
+	if i < 0 {
+		s.Unlock()
+		return errors.Errorf("unknown account %s", playerId)
+	}
+	account := s.KnownAccounts[i]
+	account.Alias = strings.TrimSpace(edit.Alias)
+	account.Tags = normalizeAccountTags(edit.Tags)
+	account.Notes = strings.TrimSpace(edit.Notes)
+	if account.Pinned != edit.Pinned {
+		account.Pinned = edit.Pinned
+		s.placeAccount(account)
+	} else {
+		s.KnownAccounts[i] = account
+	}
+	s.Unlock()
+	go s.Persist()
+	return nil
+}
+
+// MoveAccount moves a pinned account up (delta < 0) or down (delta > 0) among
+// pinned accounts, which are the only ones in an order set by the user.
+func (s *AppStorage) MoveAccount(playerId string, delta int) error {
+	s.Lock()
+	i := s.findAccount(playerId)
+	if i < 0 {
+		s.Unlock()
+		return errors.Errorf("unknown account %s", playerId)
+	}
+	if !s.KnownAccounts[i].Pinned {
+		s.Unlock()
+		return errors.Errorf("account %s isn't pinned", playerId)
+	}
+	pinned := 0
+	for pinned < len(s.KnownAccounts) && s.KnownAccounts[pinned].Pinned {
+		pinned++
+	}
+	j := i + delta
+	if j < 0 {
+		j = 0
+	}
+	if j > pinned-1 {
+		j = pinned - 1
+	}
+	account := s.KnownAccounts[i]
+	if j < i {
+		copy(s.KnownAccounts[j+1:i+1], s.KnownAccounts[j:i])
+	} else {
+		copy(s.KnownAccounts[i:j], s.KnownAccounts[i+1:j+1])
+	}
+	s.KnownAccounts[j] = account
+	s.Unlock()
+	go s.Persist()
+	return nil
+}
+
+// RemoveKnownAccount forgets an account. Storage is persisted before
+// returning, since this is also done from the command line; a storage.json
+// written by a newer version of the app is edited in place instead.
+func (s *AppStorage) RemoveKnownAccount(playerId string) {
+	s.Lock()
+	var accounts []Account
 	for _, a := range s.KnownAccounts {
-		if _, exists := seen[a.Id]; !exists {
+		if a.Id != playerId {
 			accounts = append(accounts, a)
-			seen[a.Id] = struct{}{}
 		}
 	}
 	s.KnownAccounts = accounts
+	readOnly := s.readOnly
 	s.Unlock()
-	go s.Persist()
+	if !readOnly {
+		s.Persist()
+		return
+	}
+	if err := removeAccountFromStorageFile(_storageFile, playerId); err != nil {
+		log.Errorf("error forgetting account %s in storage.json: %s", playerId, err)
+	}
+}
+
+// removeAccountFromStorageFile removes an account from known accounts in a
+// storage.json file of any version, leaving everything else as it is.
+func removeAccountFromStorageFile(path string, playerId string) error {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return err
+	}
+	var accounts []json.RawMessage
+	if raw, ok := fields["known_accounts"]; ok {
+		if err := json.Unmarshal(raw, &accounts); err != nil {
+			return err
+		}
+	}
+	kept := []json.RawMessage{}
+	for _, raw := range accounts {
+		var account struct {
+			Id string `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &account); err != nil {
+			return err
+		}
+		if account.Id != playerId {
+			kept = append(kept, raw)
+		}
+	}
+	if len(kept) == len(accounts) {
+		return nil
+	}
+	if fields["known_accounts"], err = json.Marshal(kept); err != nil {
+		return err
+	}
+	if encoded, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	return os.WriteFile(path, encoded, 0644)
+}
+
+// fillAccountMissionCounts fills in mission counts of accounts that haven't
+// been synced since they were recorded, from stored missions.
+func (s *AppStorage) fillAccountMissionCounts(count func(playerId string) (int, error)) {
+	s.Lock()
+	filled := false
+	for i, a := range s.KnownAccounts {
+		if !a.LastSyncedAt.IsZero() || a.MissionCount != 0 {
+			continue
+		}
+		n, err := count(a.Id)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		if n > 0 {
+			s.KnownAccounts[i].MissionCount = n
+			filled = true
+		}
+	}
+	s.Unlock()
+	if filled {
+		go s.Persist()
+	}
+}
+
+// findAccount returns the index of an account in KnownAccounts, or -1. The
+// caller must hold the lock.
+func (s *AppStorage) findAccount(playerId string) int {
+	for i, a := range s.KnownAccounts {
+		if a.Id == playerId {
+			return i
+		}
+	}
+	return -1
+}
+
+// placeAccount puts the account last among pinned accounts if it's pinned,
+// otherwise first among the others, replacing any account with the same ID.
+// The caller must hold the lock.
+func (s *AppStorage) placeAccount(account Account) {
+	var pinned, others []Account
+	for _, a := range s.KnownAccounts {
+		if a.Id == account.Id {
+			continue
+		}
+		if a.Pinned {
+			pinned = append(pinned, a)
+		} else {
+			others = append(others, a)
+		}
+	}
+	if account.Pinned {
+		pinned = append(pinned, account)
+	} else {
+		others = append([]Account{account}, others...)
+	}
+	s.KnownAccounts = append(pinned, others...)
+}
+
+// normalizeAccountTags trims tags and drops empty and duplicate ones, ignoring
+// case, keeping the first spelling.
+func normalizeAccountTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]struct{})
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" {
+			continue
+		}
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	return normalized
 }
 
 func (s *AppStorage) SetUpdateCheck(latestVersion string) {
@@ -83,3 +385,10 @@ func (s *AppStorage) SetUpdateCheck(latestVersion string) {
 	s.Unlock()
 	go s.Persist()
 }
+
+func (s *AppStorage) SetExportLanguage(code string) {
+	s.Lock()
+	s.ExportLanguage = code
+	s.Unlock()
+	go s.Persist()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/fanaticscripter/EggLedger/db"
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// checkUnknownValues records values in the player's missions that this version
+// of the app can't handle, usually because of a game update, and returns a
+// warning summarizing them, or "" if there are none. Such values are still
+// exported, as raw numbers.
+func checkUnknownValues(playerId string, missions missionIterator) (string, error) {
+	values := make(map[string][]ei.UnknownValue)
+	var distinct []ei.UnknownValue
+	seen := make(map[ei.UnknownValue]struct{})
+	err := missions(func(m *mission) error {
+		missionValues := ei.FindUnknownValues(m.response)
+		if len(missionValues) == 0 {
+			return nil
+		}
+		values[m.Id] = missionValues
+		for _, v := range missionValues {
+			if _, exists := seen[v]; !exists {
+				distinct = append(distinct, v)
+				seen[v] = struct{}{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	newValues, err := db.ReplaceUnknownValues(playerId, timeToUnix(time.Now()), values)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range newValues {
+		log.Warnf("%s: mission %s: unknown %s", playerId, v.MissionId, v.UnknownValue)
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	var descriptions []string
+	for _, v := range distinct {
+		descriptions = append(descriptions, v.String())
+	}
+	return fmt.Sprintf("%d missions contain values unknown to this version of EggLedger (%s), "+
+		"probably due to a game update; they are exported as raw numbers. "+
+		"Check for a new version of EggLedger or an updated artifact catalog.",
+		len(values), strings.Join(descriptions, ", ")), nil
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,3 +15,18 @@ func unixToTime(t float64) time.Time {
 	sec, dec := math.Modf(t)
 	return time.Unix(int64(sec), int64(dec*1e9))
 }
+
+var _eggAmountUnits = []string{"", "K", "M", "B", "T", "q", "Q", "s", "S", "o", "N", "d", "U", "D", "Td", "qd", "Qd", "sd", "Sd", "Od", "Nd", "V"}
+
+// formatEggAmount formats an egg amount with the oom suffixes used in game,
+// e.g. 1.5e12 => "1.5T".
+func formatEggAmount(x float64) string {
+	oom := 0
+	for math.Abs(x) >= 1000 && oom < len(_eggAmountUnits)-1 {
+		x /= 1000
+		oom++
+	}
+	s := strconv.FormatFloat(x, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + _eggAmountUnits[oom]
+}
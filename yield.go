@@ -0,0 +1,198 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/fanaticscripter/EggLedger/ei"
+)
+
+// _craftingRatios maps stone and ingredient families to the number of items of
+// each tier needed to craft one item of the next tier, starting from T1. Stone
+// fragments are T1 of the corresponding stone. Crafting recipes aren't part of
+// the artifacts configuration, so these are copied from the game and need to
+// be updated if recipes change.
+var _craftingRatios = map[ei.ArtifactSpec_Name][]float64{
+	ei.ArtifactSpec_TACHYON_STONE:   {20},
+	ei.ArtifactSpec_DILITHIUM_STONE: {20},
+	ei.ArtifactSpec_SHELL_STONE:     {20},
+	ei.ArtifactSpec_LUNAR_STONE:     {20},
+	ei.ArtifactSpec_SOUL_STONE:      {20},
+	ei.ArtifactSpec_PROPHECY_STONE:  {20},
+	ei.ArtifactSpec_QUANTUM_STONE:   {20},
+	ei.ArtifactSpec_TERRA_STONE:     {20},
+	ei.ArtifactSpec_LIFE_STONE:      {20},
+	ei.ArtifactSpec_CLARITY_STONE:   {20},
+	ei.ArtifactSpec_GOLD_METEORITE:  {11, 8},
+	ei.ArtifactSpec_TAU_CETI_GEODE:  {12, 10},
+	ei.ArtifactSpec_SOLAR_TITANIUM:  {10, 12},
+}
+
+// yieldTier returns the tier yields of the family are expressed in: the
+// highest tier craftable from fragments or lower tier ingredients, i.e. the
+// stone itself for stones, and the top tier for ingredients. Families without
+// known crafting ratios have no conversion, and yields are in T1.
+func yieldTier(family ei.ArtifactSpec_Name) int {
+	return len(_craftingRatios[family]) + 1
+}
+
+// yieldEquivalent returns the number of items of the yield tier that an item
+// of the given tier is worth, or 0 if the item is above the yield tier.
+func yieldEquivalent(family ei.ArtifactSpec_Name, tier int) float64 {
+	ratios := _craftingRatios[family]
+	if tier > len(ratios)+1 {
+		return 0
+	}
+	equivalent := 1.0
+	for t := tier; t <= len(ratios); t++ {
+		equivalent /= ratios[t-1]
+	}
+	return equivalent
+}
+
+// yieldUnit returns an item of the yield tier, e.g. a Tachyon stone or a Solid
+// gold meteorite.
+func yieldUnit(family ei.ArtifactSpec_Name) *ei.ArtifactSpec {
+	tier := yieldTier(family)
+	var level ei.ArtifactSpec_Level
+	rarity := ei.ArtifactSpec_COMMON
+	if family.ArtifactType() == ei.ArtifactSpec_STONE {
+		// Stone levels start at T2.
+		level = ei.ArtifactSpec_Level(tier - 2)
+	} else {
+		level = ei.ArtifactSpec_Level(tier - 1)
+	}
+	return &ei.ArtifactSpec{Name: &family, Level: &level, Rarity: &rarity}
+}
+
+// isYieldFamily reports whether drops of the family are crafting materials,
+// i.e. stones, which are crafted from fragments, and ingredients.
+func isYieldFamily(family ei.ArtifactSpec_Name) bool {
+	t := family.ArtifactType()
+	return t == ei.ArtifactSpec_STONE || t == ei.ArtifactSpec_INGREDIENT
+}
+
+type shipYield struct {
+	shipDurationKey
+	Family ei.ArtifactSpec_Name
+	// Missions is the total number of missions of the ship and duration, not
+	// only those that dropped the family.
+	Missions int
+	// MissionDays is the total duration of the missions in days.
+	MissionDays float64
+	// Counts maps tiers to the number of items dropped.
+	Counts map[int]int
+	// Equivalent is the number of items of the yield tier that all items up to
+	// the yield tier are worth. Items above the yield tier are only counted.
+	Equivalent float64
+}
+
+func (y *shipYield) PerMission() float64 {
+	if y.Missions == 0 {
+		return 0
+	}
+	return y.Equivalent / float64(y.Missions)
+}
+
+func (y *shipYield) PerDay() float64 {
+	if y.MissionDays == 0 {
+		return 0
+	}
+	return y.Equivalent / y.MissionDays
+}
+
+type yieldReport struct {
+	// Families lists all stone and ingredient families ever dropped, grouped
+	// by type.
+	Families []ei.ArtifactSpec_Name
+	// Yields lists yields per ship, duration and family, ordered by ship and
+	// duration.
+	Yields []*shipYield
+}
+
+// Best returns the ship and duration with the highest yield per day of
+// mission time for the family, or nil if it has never dropped.
+func (r *yieldReport) Best(family ei.ArtifactSpec_Name) *shipYield {
+	var best *shipYield
+	for _, y := range r.Yields {
+		if y.Family == family && (best == nil || y.PerDay() > best.PerDay()) {
+			best = y
+		}
+	}
+	return best
+}
+
+type yieldKey struct {
+	shipDurationKey
+	Family ei.ArtifactSpec_Name
+}
+
+// yieldSummarizer builds a yieldReport from missions added in any order.
+type yieldSummarizer struct {
+	missionCounts map[shipDurationKey]int
+	missionDays   map[shipDurationKey]float64
+	yields        map[yieldKey]*shipYield
+	seenFamilies  map[ei.ArtifactSpec_Name]struct{}
+	report        yieldReport
+}
+
+func newYieldSummarizer() *yieldSummarizer {
+	return &yieldSummarizer{
+		missionCounts: make(map[shipDurationKey]int),
+		missionDays:   make(map[shipDurationKey]float64),
+		yields:        make(map[yieldKey]*shipYield),
+		seenFamilies:  make(map[ei.ArtifactSpec_Name]struct{}),
+	}
+}
+
+func (s *yieldSummarizer) add(m *mission) {
+	key := shipDurationKey{m.Ship, m.DurationType}
+	s.missionCounts[key]++
+	s.missionDays[key] += m.DurationDays
+	for _, a := range m.Artifacts {
+		family := a.Family()
+		if !isYieldFamily(family) {
+			continue
+		}
+		if _, exists := s.seenFamilies[family]; !exists {
+			s.report.Families = append(s.report.Families, family)
+			s.seenFamilies[family] = struct{}{}
+		}
+		ykey := yieldKey{key, family}
+		y, ok := s.yields[ykey]
+		if !ok {
+			y = &shipYield{shipDurationKey: key, Family: family, Counts: make(map[int]int)}
+			s.yields[ykey] = y
+			s.report.Yields = append(s.report.Yields, y)
+		}
+		tier := a.TierNumber()
+		y.Counts[tier]++
+		y.Equivalent += yieldEquivalent(family, tier)
+	}
+}
+
+func (s *yieldSummarizer) summary() *yieldReport {
+	report := &s.report
+	for _, y := range report.Yields {
+		y.Missions = s.missionCounts[y.shipDurationKey]
+		y.MissionDays = s.missionDays[y.shipDurationKey]
+	}
+	sort.Slice(report.Families, func(i, j int) bool {
+		fi, fj := report.Families[i], report.Families[j]
+		if fi.ArtifactType() != fj.ArtifactType() {
+			return fi.ArtifactType() < fj.ArtifactType()
+		}
+		return fi.CasedName() < fj.CasedName()
+	})
+	familyOrder := make(map[ei.ArtifactSpec_Name]int)
+	for i, family := range report.Families {
+		familyOrder[family] = i
+	}
+	sort.Slice(report.Yields, func(i, j int) bool {
+		yi, yj := report.Yields[i], report.Yields[j]
+		if yi.shipDurationKey != yj.shipDurationKey {
+			return yi.less(yj.shipDurationKey)
+		}
+		return familyOrder[yi.Family] < familyOrder[yj.Family]
+	})
+	return report
+}